@@ -4,8 +4,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
-	"strings"
 
 	cfg "github.com/vecno-io/go-pyteal/config"
 	net "github.com/vecno-io/go-pyteal/network"
@@ -29,7 +27,7 @@ func Info(name, pass string) (models.Account, error) {
 
 	ac, err := LoadAccountFromFile(pass, path)
 	if nil != err {
-		return models.Account{}, fmt.Errorf("account info: load: %s", err)
+		return models.Account{}, fmt.Errorf("account info: load: %w", err)
 	}
 
 	info, err := cl.AccountInformation(ac.Address.String()).Do(context.Background())
@@ -50,16 +48,38 @@ func Load(name, pass string) (crypto.Account, error) {
 
 	acc, err := LoadAccountFromFile(pass, path)
 	if nil != err {
-		return crypto.Account{}, fmt.Errorf("load account: %s", err)
+		return crypto.Account{}, fmt.Errorf("load account: %w", err)
 	}
 
 	return acc, nil
 }
 
+// Upgrade re-encrypts a stored account with the current keystore version.
+func Upgrade(name, pass string) error {
+	path := fmt.Sprintf("%s/accounts/%s.acc", cfg.AssetPath(), name)
+	fmt.Println(":: Upgrade account:", path)
+
+	if !doesAccountExist(path) {
+		return fmt.Errorf("upgrade account: account not found: %s", path)
+	}
+
+	done, err := UpgradeAccountFile(pass, path)
+	if nil != err {
+		return fmt.Errorf("upgrade account: %w", err)
+	}
+	if done {
+		fmt.Println(">> upgraded to version", KeyStoreVersion)
+	}
+	return nil
+}
+
 func Create(name, pass string) (crypto.Account, error) {
 	path := fmt.Sprintf("%s/accounts/%s.acc", cfg.AssetPath(), name)
 	fmt.Println(":: Create account:", path)
 
+	if err := checkName(name); nil != err {
+		return crypto.Account{}, fmt.Errorf("create account: %s", err)
+	}
 	if doesAccountExist(path) {
 		return crypto.Account{}, fmt.Errorf("create account: file exists: %s", path)
 	}
@@ -72,35 +92,9 @@ func Create(name, pass string) (crypto.Account, error) {
 	return acc, nil
 }
 
-func DevFunding(address string, amount uint64) error {
-	if cfg.Target() != cfg.Devnet {
-		return fmt.Errorf("funding: only available for devnet")
-	}
-	seed, err := getDevSeedAddress()
-	if nil != err {
-		return fmt.Errorf("funding: get seed: %s", err)
-	}
-
-	cmd := fmt.Sprintf(
-		"goal -d %s/%s clerk send -a %d -f %s -t %s",
-		cfg.DataPath(), "primary", amount, seed, address,
-	)
-	fmt.Println(">>", cmd)
-	exec.Command("bash", "-c", cmd).Output()
-
-	return nil
-}
-
 func doesAccountExist(file string) bool {
 	if _, err := os.Stat(file); nil == err {
 		return true
 	}
 	return false
 }
-
-func getDevSeedAddress() (string, error) {
-	cmd := fmt.Sprintf("goal account list -d %s/%s | awk '{ print $3 }' | head -n 1", cfg.DataPath(), "primary")
-	fmt.Println(">>", cmd)
-	out, err := exec.Command("bash", "-c", cmd).Output()
-	return strings.TrimSpace(string(out)), err
-}
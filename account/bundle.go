@@ -0,0 +1,481 @@
+package acc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+	net "github.com/vecno-io/go-pyteal/network"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+// BundleId identifies bundle files.
+const BundleId = "go-pyteal-bundle"
+
+// BundleVersion is the version of newly written bundles.
+const BundleVersion = 1
+
+// Bundle protection modes.
+const (
+	BundlePassphrase = "passphrase"
+	BundleRecipient  = "recipient"
+)
+
+// Bundle entry kinds.
+const (
+	KindAccount  = "account"
+	KindMultisig = "multisig"
+	KindRecord   = "record"
+)
+
+// ErrBundleIntegrity is returned when a bundle fails to decrypt or an
+// entry does not match its checksum.
+var ErrBundleIntegrity = errors.New("bundle integrity")
+
+// ErrNameConflict is returned on import when a name is taken by other
+// content and conflicts are not resolved.
+var ErrNameConflict = errors.New("name conflict")
+
+// ConflictMode sets how an import handles names taken by other content,
+// identical content is always skipped. Renamed entries are stored as
+// <name>-imported, records are looked up by their program name and
+// can not be renamed.
+type ConflictMode int
+
+const (
+	ConflictFail ConflictMode = iota
+	ConflictSkip
+	ConflictRename
+	ConflictOverwrite
+)
+
+// BundleItems selects what to export by name. Keystores are copied
+// as is, they keep their own passphrase.
+type BundleItems struct {
+	Accounts  []string
+	Multisigs []string
+	Records   []string
+}
+
+// BundleOptions protects a bundle with a passphrase, or the box public
+// key of the recipient when set.
+type BundleOptions struct {
+	Passphrase string
+	Recipient  *[32]byte
+}
+
+// ImportOptions opens a bundle with the passphrase, or the box private
+// key of the recipient when set.
+type ImportOptions struct {
+	Passphrase string
+	PrivateKey *[32]byte
+	Conflict   ConflictMode
+}
+
+// BundleFile is the stored bundle, Data holds the encrypted content.
+type BundleFile struct {
+	Id   string  `json:"id"`
+	Ver  uint64  `json:"ver"`
+	Mode string  `json:"mode"`
+	Key  KeyInfo `json:"key,omitempty"`
+	Box  string  `json:"box,omitempty"`
+	Data KeyData `json:"data"`
+}
+
+// BundleEntry is a file in the bundle with its sha256 checksum.
+type BundleEntry struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Data []byte `json:"data"`
+	Sum  string `json:"sum"`
+}
+
+type bundleContent struct {
+	Created time.Time     `json:"created"`
+	Entries []BundleEntry `json:"entries"`
+}
+
+// BundleResult is the outcome of importing an entry, As is the name it
+// was stored under.
+type BundleResult struct {
+	Kind   string
+	Name   string
+	As     string
+	Action string
+}
+
+// GenerateBundleKey returns a box key pair to receive bundles.
+func GenerateBundleKey() (*[32]byte, *[32]byte, error) {
+	return box.GenerateKey(rand.Reader)
+}
+
+func recordPath(name string) string {
+	return fmt.Sprintf("%s/%s.id", cfg.AssetPath(), name)
+}
+
+func entryPath(kind, name string) string {
+	switch kind {
+	case KindAccount:
+		return accountPath(name)
+	case KindMultisig:
+		return multisigPath(name)
+	case KindRecord:
+		return recordPath(name)
+	}
+	return ""
+}
+
+// ExportBundle writes the selected items to an encrypted bundle.
+func ExportBundle(path string, items BundleItems, opts BundleOptions) error {
+	fmt.Println(":: Export bundle:", path)
+
+	content := bundleContent{Created: time.Now().UTC()}
+	add := func(kind string, names []string) error {
+		for _, name := range names {
+			data, err := os.ReadFile(entryPath(kind, name))
+			if err != nil {
+				return fmt.Errorf("%s %s: %s", kind, name, err)
+			}
+			sum := sha256.Sum256(data)
+			content.Entries = append(content.Entries, BundleEntry{
+				Kind: kind, Name: name, Data: data, Sum: hex.EncodeToString(sum[:]),
+			})
+		}
+		return nil
+	}
+	if err := add(KindAccount, items.Accounts); nil != err {
+		return fmt.Errorf("export bundle: %s", err)
+	}
+	if err := add(KindMultisig, items.Multisigs); nil != err {
+		return fmt.Errorf("export bundle: %s", err)
+	}
+	if err := add(KindRecord, items.Records); nil != err {
+		return fmt.Errorf("export bundle: %s", err)
+	}
+	if err := writeBundle(path, content, opts); nil != err {
+		return fmt.Errorf("export bundle: %s", err)
+	}
+	fmt.Printf(">> %d entries\n", len(content.Entries))
+	return nil
+}
+
+// writeBundle encrypts the content to a bundle file.
+func writeBundle(path string, content bundleContent, opts BundleOptions) error {
+	plain, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+
+	file := BundleFile{Id: BundleId, Ver: BundleVersion}
+	if nil != opts.Recipient {
+		file.Mode = BundleRecipient
+		pub, priv, err := box.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		nonce := [24]byte{}
+		if _, err := rand.Read(nonce[:]); nil != err {
+			return err
+		}
+		file.Box = hex.EncodeToString(pub[:])
+		file.Data = KeyData{
+			S: hex.EncodeToString(nonce[:]),
+			D: hex.EncodeToString(box.Seal(nil, plain, &nonce, opts.Recipient, priv)),
+		}
+	} else {
+		if len(opts.Passphrase) == 0 {
+			return fmt.Errorf("no passphrase or recipient")
+		}
+		file.Mode = BundlePassphrase
+		key, err := bundleKdf(&file, opts.Passphrase)
+		if err != nil {
+			return err
+		}
+		if file.Data, err = gcmEncrypt(string(plain), key, file.additionalData()); nil != err {
+			return err
+		}
+	}
+
+	out, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, out)
+}
+
+// bundleKdf sets the key derivation of a new bundle, the configured
+// profile is used unless it is weaker than standard.
+func bundleKdf(file *BundleFile, pass string) ([]byte, error) {
+	profile, err := ConfiguredKdf()
+	if err != nil {
+		return nil, err
+	}
+	if standard := KdfProfiles["standard"]; profile.Cost() < standard.Cost() {
+		profile = standard
+	}
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	file.Key = profile.info(hex.EncodeToString(salt))
+	return deriveKey(pass, salt, file.Key, KeyStoreVersion)
+}
+
+func (f BundleFile) additionalData() []byte {
+	return []byte(fmt.Sprintf("%s:%d:%s", f.Id, f.Ver, f.Mode))
+}
+
+// ImportBundle verifies a bundle and stores its entries, nothing is
+// written when an entry fails to verify or a conflict is unresolved.
+func ImportBundle(path string, opts ImportOptions) ([]BundleResult, error) {
+	fmt.Println(":: Import bundle:", path)
+
+	content, err := openBundle(path, opts)
+	if err != nil {
+		return nil, fmt.Errorf("import bundle: %w", err)
+	}
+	for _, e := range content.Entries {
+		if err := verifyEntry(e); nil != err {
+			return nil, fmt.Errorf("import bundle: %s %s: %w", e.Kind, e.Name, err)
+		}
+		if e.Kind != KindRecord {
+			continue
+		}
+		if err := checkRecordGenesis(e); nil != err {
+			return nil, fmt.Errorf("import bundle: %s %s: %w", e.Kind, e.Name, err)
+		}
+	}
+
+	// Plan all entries first, the files are only written when all resolve
+	results := make([]BundleResult, len(content.Entries))
+	taken := map[string]bool{}
+	for i, e := range content.Entries {
+		r, err := planEntry(e, opts.Conflict, taken)
+		if err != nil {
+			return nil, fmt.Errorf("import bundle: %w", err)
+		}
+		taken[entryPath(e.Kind, r.As)] = true
+		results[i] = r
+	}
+
+	for i, e := range content.Entries {
+		r := results[i]
+		if r.Action == "skipped" || r.Action == "identical" {
+			continue
+		}
+		dst := entryPath(e.Kind, r.As)
+		if r.Action == "overwritten" {
+			if _, err := backupFile(dst); nil != err && !os.IsNotExist(err) {
+				return results, fmt.Errorf("import bundle: backup %s: %s", r.As, err)
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0700); nil != err {
+			return results, fmt.Errorf("import bundle: %s", err)
+		}
+		if err := writeFileAtomic(dst, e.Data); nil != err {
+			return results, fmt.Errorf("import bundle: %s", err)
+		}
+	}
+	for _, r := range results {
+		fmt.Printf(">> %s %s: %s %s\n", r.Kind, r.Name, r.Action, r.As)
+	}
+	return results, nil
+}
+
+func openBundle(path string, opts ImportOptions) (bundleContent, error) {
+	content := bundleContent{}
+	in, err := os.ReadFile(path)
+	if err != nil {
+		return content, err
+	}
+	file := BundleFile{}
+	if err := json.Unmarshal(in, &file); nil != err {
+		return content, err
+	}
+	if file.Id != BundleId || file.Ver != BundleVersion {
+		return content, fmt.Errorf("unsupported bundle: %s (version %d)", file.Id, file.Ver)
+	}
+
+	var plain []byte
+	switch file.Mode {
+	case BundleRecipient:
+		if nil == opts.PrivateKey {
+			return content, fmt.Errorf("bundle is for a recipient key")
+		}
+		pub, nonce := [32]byte{}, [24]byte{}
+		if err := decodeFixed(file.Box, pub[:]); nil != err {
+			return content, fmt.Errorf("box key: %s", err)
+		}
+		if err := decodeFixed(file.Data.S, nonce[:]); nil != err {
+			return content, fmt.Errorf("nonce: %s", err)
+		}
+		sealed, err := hex.DecodeString(file.Data.D)
+		if err != nil {
+			return content, err
+		}
+		var ok bool
+		if plain, ok = box.Open(nil, sealed, &nonce, &pub, opts.PrivateKey); !ok {
+			return content, ErrBundleIntegrity
+		}
+	case BundlePassphrase:
+		salt, err := hex.DecodeString(file.Key.S)
+		if err != nil {
+			return content, err
+		}
+		key, err := deriveKey(opts.Passphrase, salt, file.Key, KeyStoreVersion)
+		if err != nil {
+			return content, err
+		}
+		data, err := gcmDecrypt(file.Data, key, file.additionalData())
+		if errors.Is(err, ErrWrongPassphrase) {
+			return content, fmt.Errorf("%w or %s", ErrBundleIntegrity, err)
+		}
+		if err != nil {
+			return content, err
+		}
+		plain = []byte(data)
+	default:
+		return content, fmt.Errorf("unsupported bundle mode: %s", file.Mode)
+	}
+
+	if err := json.Unmarshal(plain, &content); nil != err {
+		return content, fmt.Errorf("%w: %s", ErrBundleIntegrity, err)
+	}
+	return content, nil
+}
+
+func decodeFixed(s string, out []byte) error {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(data) != len(out) {
+		return fmt.Errorf("invalid size: %d", len(data))
+	}
+	copy(out, data)
+	return nil
+}
+
+// verifyEntry checks the checksum and that the entry is a valid file
+// of its kind.
+func verifyEntry(e BundleEntry) error {
+	sum := sha256.Sum256(e.Data)
+	if hex.EncodeToString(sum[:]) != e.Sum {
+		return fmt.Errorf("checksum: %w", ErrBundleIntegrity)
+	}
+	if err := checkName(e.Name); nil != err {
+		return err
+	}
+	switch e.Kind {
+	case KindAccount:
+		store := KeyStore{}
+		if err := json.Unmarshal(e.Data, &store); nil != err {
+			return err
+		}
+		if len(store.Addr) == 0 {
+			return fmt.Errorf("keystore has no address, upgrade it before export")
+		}
+	case KindMultisig:
+		m := Multisig{}
+		if err := json.Unmarshal(e.Data, &m); nil != err {
+			return err
+		}
+		addr, err := m.Address()
+		if err != nil {
+			return err
+		}
+		if addr.String() != m.Addr {
+			return fmt.Errorf("address mismatch: %s", m.Addr)
+		}
+	case KindRecord:
+		if !json.Valid(e.Data) {
+			return fmt.Errorf("invalid record")
+		}
+	default:
+		return fmt.Errorf("unknown entry kind")
+	}
+	return nil
+}
+
+// checkRecordGenesis checks a deploy record is for the network of the
+// target, records of other networks point to apps that do not exist.
+func checkRecordGenesis(e BundleEntry) error {
+	rec := struct {
+		GenesisId   string `json:"genesis_id"`
+		GenesisHash string `json:"genesis_hash"`
+	}{}
+	if err := json.Unmarshal(e.Data, &rec); nil != err {
+		return err
+	}
+	if len(rec.GenesisId) == 0 || len(rec.GenesisHash) == 0 {
+		return fmt.Errorf("record has no genesis, deploy it again")
+	}
+	id, err := net.ExpectedGenesis()
+	if err != nil {
+		return err
+	}
+	if rec.GenesisId != id.Id || rec.GenesisHash != id.Hash {
+		return fmt.Errorf("%w: record of %s, target is %s", net.ErrGenesisMismatch, rec.GenesisId, id.Id)
+	}
+	return nil
+}
+
+// planEntry resolves where an entry is stored, taken holds the paths
+// already claimed by earlier entries of the bundle.
+func planEntry(e BundleEntry, mode ConflictMode, taken map[string]bool) (BundleResult, error) {
+	r := BundleResult{Kind: e.Kind, Name: e.Name, As: e.Name, Action: "imported"}
+
+	// An account stored under another name is not imported twice
+	if e.Kind == KindAccount {
+		store := KeyStore{}
+		json.Unmarshal(e.Data, &store)
+		if stored, err := Find(store.Addr); nil == err && stored.Name != e.Name {
+			r.As, r.Action = stored.Name, "skipped"
+			return r, nil
+		}
+	}
+
+	path := entryPath(e.Kind, e.Name)
+	existing, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !taken[path] {
+		return r, nil
+	}
+	if nil == err && bytes.Equal(existing, e.Data) {
+		r.Action = "identical"
+		return r, nil
+	}
+
+	switch mode {
+	case ConflictSkip:
+		r.Action = "skipped"
+	case ConflictOverwrite:
+		r.Action = "overwritten"
+	case ConflictRename:
+		if e.Kind == KindRecord {
+			return r, fmt.Errorf("%s %s: records can not be renamed: %w", e.Kind, e.Name, ErrNameConflict)
+		}
+		for n := 1; ; n++ {
+			name := fmt.Sprintf("%s-imported", e.Name)
+			if n > 1 {
+				name = fmt.Sprintf("%s-imported-%d", e.Name, n)
+			}
+			p := entryPath(e.Kind, name)
+			if _, err := os.Stat(p); os.IsNotExist(err) && !taken[p] {
+				r.As, r.Action = name, "renamed"
+				break
+			}
+		}
+	default:
+		return r, fmt.Errorf("%s %s: %w", e.Kind, e.Name, ErrNameConflict)
+	}
+	return r, nil
+}
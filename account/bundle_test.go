@@ -0,0 +1,395 @@
+package acc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+	net "github.com/vecno-io/go-pyteal/network"
+
+	"github.com/algorand/go-algorand-sdk/crypto"
+)
+
+const (
+	bundleGenesisId   = "bundle-v1"
+	bundleGenesisHash = "YnVuZGxlIHRlc3QgZ2VuZXNpcyBoYXNoIDMyIGJ5dGU="
+)
+
+// setupAssets points the config to an empty devnet asset path.
+func setupAssets(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	node, asset := filepath.Join(dir, "node"), filepath.Join(dir, "asset")
+	for _, d := range []string{node, filepath.Join(asset, "images"), filepath.Join(asset, "contracts"), filepath.Join(asset, "accounts")} {
+		if err := os.MkdirAll(d, 0700); nil != err {
+			t.Fatal(err)
+		}
+	}
+	for _, bin := range []string{"algod", "goal", "kmd"} {
+		if err := os.WriteFile(filepath.Join(node, bin), nil, 0700); nil != err {
+			t.Fatal(err)
+		}
+	}
+	err := cfg.OnInitialize(cfg.Setup{
+		Target:    "devnet",
+		NodePath:  node,
+		AssetPath: asset,
+		Kdf:       "fast",
+		Genesis:   cfg.GenesisSetup{Id: bundleGenesisId, Hash: bundleGenesisHash},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cfg.OnCreate(cfg.Setup{Target: "devnet"})
+	})
+	return asset
+}
+
+// bundleFixture stores an account, a multisig and a record.
+func bundleFixture(t *testing.T) (BundleItems, map[string][]byte) {
+	t.Helper()
+	storeAccount(t, "alice")
+	if _, err := CreateMultisig("team", 1, []MultisigMember{
+		{Name: "alice"}, {Addr: crypto.GenerateAccount().Address.String()},
+	}); nil != err {
+		t.Fatal(err)
+	}
+	writeRecord(t, "app", bundleGenesisId, bundleGenesisHash)
+
+	items := BundleItems{Accounts: []string{"alice"}, Multisigs: []string{"team"}, Records: []string{"app"}}
+	return items, map[string][]byte{
+		accountPath("alice"): readFile(t, accountPath("alice")),
+		multisigPath("team"): readFile(t, multisigPath("team")),
+		recordPath("app"):    readFile(t, recordPath("app")),
+	}
+}
+
+func storeAccount(t *testing.T, name string) crypto.Account {
+	t.Helper()
+	a := crypto.GenerateAccount()
+	if err := SaveAccountToFileWith(a, "pass", accountPath(name), KdfProfiles["fast"]); nil != err {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func writeRecord(t *testing.T, name, id, hash string) {
+	t.Helper()
+	data := fmt.Sprintf(`{"id":5,"genesis_id":%q,"genesis_hash":%q}`, id, hash)
+	if err := os.WriteFile(recordPath(name), []byte(data), 0600); nil != err {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func checkFiles(t *testing.T, files map[string][]byte) {
+	t.Helper()
+	for path, data := range files {
+		if got := readFile(t, path); !bytes.Equal(got, data) {
+			t.Fatalf("%s: content differs", path)
+		}
+	}
+}
+
+// rebase moves the fixture paths to the current asset path.
+func rebase(files map[string][]byte, from, to string) map[string][]byte {
+	out := map[string][]byte{}
+	for path, data := range files {
+		rel, _ := filepath.Rel(from, path)
+		out[filepath.Join(to, rel)] = data
+	}
+	return out
+}
+
+func editBundle(t *testing.T, path string, fn func(*BundleFile)) {
+	t.Helper()
+	file := BundleFile{}
+	if err := readJson(path, &file); nil != err {
+		t.Fatal(err)
+	}
+	fn(&file)
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); nil != err {
+		t.Fatal(err)
+	}
+}
+
+func flipHex(s string) string {
+	data, _ := hex.DecodeString(s)
+	data[len(data)/2] ^= 0x01
+	return hex.EncodeToString(data)
+}
+
+func TestBundlePassphrase(t *testing.T) {
+	from := setupAssets(t)
+	items, files := bundleFixture(t)
+	path := filepath.Join(t.TempDir(), "export.bundle")
+	if err := ExportBundle(path, items, BundleOptions{Passphrase: "bundle pass"}); nil != err {
+		t.Fatalf("export: %s", err)
+	}
+
+	to := setupAssets(t)
+	if _, err := ImportBundle(path, ImportOptions{Passphrase: "wrong pass"}); !errors.Is(err, ErrBundleIntegrity) {
+		t.Fatalf("expected %s, got %v", ErrBundleIntegrity, err)
+	}
+	results, err := ImportBundle(path, ImportOptions{Passphrase: "bundle pass"})
+	if err != nil {
+		t.Fatalf("import: %s", err)
+	}
+	for _, r := range results {
+		if r.Action != "imported" || r.As != r.Name {
+			t.Fatalf("unexpected result: %+v", r)
+		}
+	}
+	checkFiles(t, rebase(files, from, to))
+}
+
+func TestBundleRecipient(t *testing.T) {
+	from := setupAssets(t)
+	items, files := bundleFixture(t)
+	pub, priv, err := GenerateBundleKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "export.bundle")
+	if err := ExportBundle(path, items, BundleOptions{Recipient: pub}); nil != err {
+		t.Fatalf("export: %s", err)
+	}
+
+	to := setupAssets(t)
+	if _, err := ImportBundle(path, ImportOptions{}); nil == err {
+		t.Fatalf("import without key: expected an error")
+	}
+	_, other, _ := GenerateBundleKey()
+	if _, err := ImportBundle(path, ImportOptions{PrivateKey: other}); !errors.Is(err, ErrBundleIntegrity) {
+		t.Fatalf("expected %s, got %v", ErrBundleIntegrity, err)
+	}
+	if _, err := ImportBundle(path, ImportOptions{PrivateKey: priv}); nil != err {
+		t.Fatalf("import: %s", err)
+	}
+	checkFiles(t, rebase(files, from, to))
+}
+
+func TestBundleTampered(t *testing.T) {
+	setupAssets(t)
+	items, _ := bundleFixture(t)
+	pub, priv, err := GenerateBundleKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		opts   BundleOptions
+		tamper func(*BundleFile)
+	}{
+		{"salt", BundleOptions{Passphrase: "pass"}, func(f *BundleFile) { f.Key.S = flipHex(f.Key.S) }},
+		{"nonce", BundleOptions{Passphrase: "pass"}, func(f *BundleFile) { f.Data.S = flipHex(f.Data.S) }},
+		{"ciphertext", BundleOptions{Passphrase: "pass"}, func(f *BundleFile) { f.Data.D = flipHex(f.Data.D) }},
+		{"box key", BundleOptions{Recipient: pub}, func(f *BundleFile) { f.Box = flipHex(f.Box) }},
+		{"box ciphertext", BundleOptions{Recipient: pub}, func(f *BundleFile) { f.Data.D = flipHex(f.Data.D) }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "export.bundle")
+			if err := ExportBundle(path, items, test.opts); nil != err {
+				t.Fatalf("export: %s", err)
+			}
+			editBundle(t, path, test.tamper)
+
+			_, err := ImportBundle(path, ImportOptions{Passphrase: "pass", PrivateKey: priv})
+			if !errors.Is(err, ErrBundleIntegrity) {
+				t.Fatalf("expected %s, got %v", ErrBundleIntegrity, err)
+			}
+		})
+	}
+}
+
+func TestBundleChecksum(t *testing.T) {
+	setupAssets(t)
+	data := []byte(`{"id":5}`)
+	sum := sha256.Sum256([]byte(`{"id":6}`))
+	content := bundleContent{Created: time.Now().UTC(), Entries: []BundleEntry{{
+		Kind: KindRecord, Name: "app", Data: data, Sum: hex.EncodeToString(sum[:]),
+	}}}
+	path := filepath.Join(t.TempDir(), "export.bundle")
+	if err := writeBundle(path, content, BundleOptions{Passphrase: "pass"}); nil != err {
+		t.Fatal(err)
+	}
+
+	if _, err := ImportBundle(path, ImportOptions{Passphrase: "pass"}); !errors.Is(err, ErrBundleIntegrity) {
+		t.Fatalf("expected %s, got %v", ErrBundleIntegrity, err)
+	}
+	if _, err := os.Stat(recordPath("app")); !os.IsNotExist(err) {
+		t.Fatalf("record written: %v", err)
+	}
+}
+
+func TestBundleConflict(t *testing.T) {
+	// conflicts replaces the fixture with other content of the same names
+	conflicts := func(t *testing.T, items BundleItems) (string, map[string][]byte) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "export.bundle")
+		if err := ExportBundle(path, items, BundleOptions{Passphrase: "pass"}); nil != err {
+			t.Fatalf("export: %s", err)
+		}
+		storeAccount(t, "alice")
+		if err := os.WriteFile(multisigPath("team"), []byte(`{}`), 0600); nil != err {
+			t.Fatal(err)
+		}
+		writeRecord(t, "app", bundleGenesisId, "")
+		return path, map[string][]byte{
+			accountPath("alice"): readFile(t, accountPath("alice")),
+			multisigPath("team"): readFile(t, multisigPath("team")),
+			recordPath("app"):    readFile(t, recordPath("app")),
+		}
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		setupAssets(t)
+		items, _ := bundleFixture(t)
+		path, local := conflicts(t, items)
+
+		_, err := ImportBundle(path, ImportOptions{Passphrase: "pass", Conflict: ConflictFail})
+		if !errors.Is(err, ErrNameConflict) {
+			t.Fatalf("expected %s, got %v", ErrNameConflict, err)
+		}
+		checkFiles(t, local)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		setupAssets(t)
+		items, _ := bundleFixture(t)
+		path, local := conflicts(t, items)
+
+		results, err := ImportBundle(path, ImportOptions{Passphrase: "pass", Conflict: ConflictSkip})
+		if err != nil {
+			t.Fatalf("import: %s", err)
+		}
+		for _, r := range results {
+			if r.Action != "skipped" {
+				t.Fatalf("unexpected result: %+v", r)
+			}
+		}
+		checkFiles(t, local)
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		setupAssets(t)
+		items, files := bundleFixture(t)
+		items.Records = nil
+		path, local := conflicts(t, items)
+
+		results, err := ImportBundle(path, ImportOptions{Passphrase: "pass", Conflict: ConflictRename})
+		if err != nil {
+			t.Fatalf("import: %s", err)
+		}
+		for _, r := range results {
+			if r.Action != "renamed" || r.As != r.Name+"-imported" {
+				t.Fatalf("unexpected result: %+v", r)
+			}
+		}
+		checkFiles(t, local)
+		checkFiles(t, map[string][]byte{
+			accountPath("alice-imported"): files[accountPath("alice")],
+			multisigPath("team-imported"): files[multisigPath("team")],
+		})
+	})
+
+	t.Run("rename record", func(t *testing.T) {
+		setupAssets(t)
+		items, _ := bundleFixture(t)
+		path, local := conflicts(t, items)
+
+		_, err := ImportBundle(path, ImportOptions{Passphrase: "pass", Conflict: ConflictRename})
+		if !errors.Is(err, ErrNameConflict) {
+			t.Fatalf("expected %s, got %v", ErrNameConflict, err)
+		}
+		checkFiles(t, local)
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		asset := setupAssets(t)
+		items, files := bundleFixture(t)
+		path, _ := conflicts(t, items)
+
+		results, err := ImportBundle(path, ImportOptions{Passphrase: "pass", Conflict: ConflictOverwrite})
+		if err != nil {
+			t.Fatalf("import: %s", err)
+		}
+		for _, r := range results {
+			if r.Action != "overwritten" {
+				t.Fatalf("unexpected result: %+v", r)
+			}
+		}
+		checkFiles(t, files)
+		for _, pattern := range []string{
+			filepath.Join(accountsPath(), "backup", "alice.*.acc"),
+			filepath.Join(accountsPath(), "backup", "team.*.msig"),
+			filepath.Join(asset, "backup", "app.*.id"),
+		} {
+			if m, _ := filepath.Glob(pattern); len(m) != 1 {
+				t.Fatalf("backup %s: found %d", pattern, len(m))
+			}
+		}
+	})
+}
+
+func TestBundleDuplicateAddress(t *testing.T) {
+	setupAssets(t)
+	storeAccount(t, "alice")
+	alice := readFile(t, accountPath("alice"))
+	path := filepath.Join(t.TempDir(), "export.bundle")
+	if err := ExportBundle(path, BundleItems{Accounts: []string{"alice"}}, BundleOptions{Passphrase: "pass"}); nil != err {
+		t.Fatalf("export: %s", err)
+	}
+
+	setupAssets(t)
+	if err := os.WriteFile(accountPath("bob"), alice, 0600); nil != err {
+		t.Fatal(err)
+	}
+	results, err := ImportBundle(path, ImportOptions{Passphrase: "pass"})
+	if err != nil {
+		t.Fatalf("import: %s", err)
+	}
+	if r := results[0]; r.Action != "skipped" || r.As != "bob" {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+	if _, err := os.Stat(accountPath("alice")); !os.IsNotExist(err) {
+		t.Fatalf("account written: %v", err)
+	}
+}
+
+func TestBundleRecordGenesis(t *testing.T) {
+	setupAssets(t)
+	writeRecord(t, "app", "other-v1", bundleGenesisHash)
+	path := filepath.Join(t.TempDir(), "export.bundle")
+	if err := ExportBundle(path, BundleItems{Records: []string{"app"}}, BundleOptions{Passphrase: "pass"}); nil != err {
+		t.Fatalf("export: %s", err)
+	}
+
+	setupAssets(t)
+	if _, err := ImportBundle(path, ImportOptions{Passphrase: "pass"}); !errors.Is(err, net.ErrGenesisMismatch) {
+		t.Fatalf("expected %s, got %v", net.ErrGenesisMismatch, err)
+	}
+}
@@ -0,0 +1,544 @@
+package acc
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	stdnet "net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// ErrPolicyDenied is returned when a transaction is outside the policy
+// of the signing key.
+var ErrPolicyDenied = errors.New("denied by policy")
+
+// DefaultMaxFee is the fee limit of a key with any limit and no
+// MaxFee, ten times the minimum fee.
+const DefaultMaxFee = 10000
+
+// KeyPolicy limits what a daemon key signs, empty lists and zero
+// amounts are no limit. Receivers covers close and rekey targets and
+// the asset config addresses, the app id 0 allows app creation. A key
+// with any limit only signs the types in Types, by default payments,
+// asset transfers and app calls, and fees up to DefaultMaxFee. The fee
+// of a payment counts against MaxAmount.
+type KeyPolicy struct {
+	Types          []types.TxType `json:"types,omitempty"`
+	Receivers      []string       `json:"receivers,omitempty"`
+	MaxAmount      uint64         `json:"max_amount,omitempty"`
+	MaxFee         uint64         `json:"max_fee,omitempty"`
+	MaxAssetAmount uint64         `json:"max_asset_amount,omitempty"`
+	AppIds         []uint64       `json:"app_ids,omitempty"`
+	AllowRekey     bool           `json:"allow_rekey,omitempty"`
+	AllowClose     bool           `json:"allow_close,omitempty"`
+}
+
+// Check returns an ErrPolicyDenied error when tx is not allowed.
+func (p KeyPolicy) Check(tx types.Transaction) error {
+	deny := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), ErrPolicyDenied)
+	}
+	if p.restricted() && !p.allowsType(tx.Type) {
+		return deny("type %s not allowed", tx.Type)
+	}
+	if max := p.maxFee(); max > 0 && uint64(tx.Fee) > max {
+		return deny("fee %d above %d", tx.Fee, max)
+	}
+	if !tx.RekeyTo.IsZero() {
+		if !p.AllowRekey {
+			return deny("rekey not allowed")
+		}
+		if !p.receiver(tx.RekeyTo) {
+			return deny("rekey to %s", tx.RekeyTo)
+		}
+	}
+
+	switch tx.Type {
+	case types.PaymentTx:
+		if p.MaxAmount > 0 && uint64(tx.Amount)+uint64(tx.Fee) > p.MaxAmount {
+			return deny("amount %d with fee %d above %d", tx.Amount, tx.Fee, p.MaxAmount)
+		}
+		if !p.receiver(tx.Receiver) {
+			return deny("receiver %s", tx.Receiver)
+		}
+		if !tx.CloseRemainderTo.IsZero() && (!p.AllowClose || !p.receiver(tx.CloseRemainderTo)) {
+			return deny("close to %s", tx.CloseRemainderTo)
+		}
+	case types.AssetTransferTx:
+		if p.MaxAssetAmount > 0 && tx.AssetAmount > p.MaxAssetAmount {
+			return deny("asset amount %d above %d", tx.AssetAmount, p.MaxAssetAmount)
+		}
+		if tx.AssetReceiver != tx.Sender && !p.receiver(tx.AssetReceiver) {
+			return deny("asset receiver %s", tx.AssetReceiver)
+		}
+		if !tx.AssetCloseTo.IsZero() && (!p.AllowClose || !p.receiver(tx.AssetCloseTo)) {
+			return deny("asset close to %s", tx.AssetCloseTo)
+		}
+	case types.ApplicationCallTx:
+		if len(p.AppIds) > 0 && !containsId(p.AppIds, uint64(tx.ApplicationID)) {
+			return deny("app %d", tx.ApplicationID)
+		}
+	case types.AssetConfigTx:
+		ap := tx.AssetParams
+		for _, addr := range []types.Address{ap.Manager, ap.Reserve, ap.Freeze, ap.Clawback} {
+			if !addr.IsZero() && addr != tx.Sender && !p.receiver(addr) {
+				return deny("asset config address %s", addr)
+			}
+		}
+	}
+	return nil
+}
+
+// restricted reports whether the policy sets any limit.
+func (p KeyPolicy) restricted() bool {
+	return len(p.Types) > 0 || len(p.Receivers) > 0 || len(p.AppIds) > 0 ||
+		p.MaxAmount > 0 || p.MaxAssetAmount > 0 || p.MaxFee > 0
+}
+
+func (p KeyPolicy) maxFee() uint64 {
+	if p.MaxFee == 0 && p.restricted() {
+		return DefaultMaxFee
+	}
+	return p.MaxFee
+}
+
+func (p KeyPolicy) allowsType(t types.TxType) bool {
+	allowed := p.Types
+	if len(allowed) == 0 {
+		allowed = []types.TxType{types.PaymentTx, types.AssetTransferTx, types.ApplicationCallTx}
+	}
+	for _, a := range allowed {
+		if a == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (p KeyPolicy) receiver(addr types.Address) bool {
+	if len(p.Receivers) == 0 {
+		return true
+	}
+	for _, r := range p.Receivers {
+		if r == addr.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func containsId(ids []uint64, id uint64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// DaemonOptions sets where the daemon listens, a unix socket when
+// Socket is set, else Addr which has to be a loopback address.
+type DaemonOptions struct {
+	Socket   string
+	Addr     string
+	Token    string
+	AuditLog string
+}
+
+// DefaultDaemonOptions listens on <asset>/signer.sock and audits to
+// <asset>/audit/signer.log, the token still has to be set.
+func DefaultDaemonOptions() DaemonOptions {
+	return DaemonOptions{
+		Socket:   fmt.Sprintf("%s/signer.sock", cfg.AssetPath()),
+		AuditLog: fmt.Sprintf("%s/audit/signer.log", cfg.AssetPath()),
+	}
+}
+
+// DaemonKey is a key served by the daemon.
+type DaemonKey struct {
+	Name    string    `json:"name"`
+	Address string    `json:"address"`
+	Policy  KeyPolicy `json:"policy"`
+}
+
+// SignRequest signs the msgpack encoded transactions with a key.
+type SignRequest struct {
+	Key  string   `json:"key"`
+	Txns [][]byte `json:"txns"`
+}
+
+// SignResponse holds the msgpack encoded signed transactions.
+type SignResponse struct {
+	Signed [][]byte `json:"signed"`
+}
+
+// AuditEntry is a line of the audit log, one per transaction.
+type AuditEntry struct {
+	Time     time.Time    `json:"time"`
+	Key      string       `json:"key"`
+	TxId     string       `json:"txid,omitempty"`
+	Type     types.TxType `json:"type,omitempty"`
+	Sender   string       `json:"sender,omitempty"`
+	Receiver string       `json:"receiver,omitempty"`
+	Amount   uint64       `json:"amount,omitempty"`
+	AppId    uint64       `json:"app_id,omitempty"`
+	Result   string       `json:"result"`
+}
+
+// Daemon holds unlocked accounts and signs for local clients.
+type Daemon struct {
+	opts DaemonOptions
+
+	mu    sync.Mutex
+	keys  map[string]daemonKey
+	audit *os.File
+
+	listener stdnet.Listener
+	server   *http.Server
+}
+
+type daemonKey struct {
+	account crypto.Account
+	policy  KeyPolicy
+}
+
+func NewDaemon(opts DaemonOptions) (*Daemon, error) {
+	if len(opts.Token) == 0 {
+		return nil, fmt.Errorf("signer daemon: no token")
+	}
+	if len(opts.AuditLog) == 0 {
+		return nil, fmt.Errorf("signer daemon: no audit log")
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.AuditLog), 0700); nil != err {
+		return nil, fmt.Errorf("signer daemon: %s", err)
+	}
+	audit, err := os.OpenFile(opts.AuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("signer daemon: %s", err)
+	}
+	return &Daemon{
+		opts:  opts,
+		keys:  map[string]daemonKey{},
+		audit: audit,
+	}, nil
+}
+
+// Unlock loads a stored account to sign with under the policy.
+func (d *Daemon) Unlock(name, pass string, policy KeyPolicy) error {
+	a, err := Load(name, pass)
+	if err != nil {
+		return fmt.Errorf("signer daemon: %w", err)
+	}
+	d.mu.Lock()
+	d.keys[name] = daemonKey{account: a, policy: policy}
+	d.mu.Unlock()
+	d.log(AuditEntry{Key: name, Sender: a.Address.String(), Result: "unlocked"})
+	return nil
+}
+
+// Start listens and serves in the background until Close.
+func (d *Daemon) Start() error {
+	var err error
+	if len(d.opts.Socket) > 0 {
+		os.Remove(d.opts.Socket)
+		if d.listener, err = stdnet.Listen("unix", d.opts.Socket); nil != err {
+			return fmt.Errorf("signer daemon: %s", err)
+		}
+		if err := os.Chmod(d.opts.Socket, 0600); nil != err {
+			d.listener.Close()
+			return fmt.Errorf("signer daemon: %s", err)
+		}
+	} else {
+		if err := checkLoopback(d.opts.Addr); nil != err {
+			return fmt.Errorf("signer daemon: %s", err)
+		}
+		if d.listener, err = stdnet.Listen("tcp", d.opts.Addr); nil != err {
+			return fmt.Errorf("signer daemon: %s", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/keys", d.auth(d.handleKeys))
+	mux.HandleFunc("/v1/sign", d.auth(d.handleSign))
+	d.server = &http.Server{Handler: mux}
+	go d.server.Serve(d.listener)
+	fmt.Println(":: Signer daemon:", d.Endpoint())
+	return nil
+}
+
+// Endpoint is the address clients connect to, unix://<path> for
+// a socket or http://<host:port>.
+func (d *Daemon) Endpoint() string {
+	if len(d.opts.Socket) > 0 {
+		return "unix://" + d.opts.Socket
+	}
+	if nil != d.listener {
+		return "http://" + d.listener.Addr().String()
+	}
+	return "http://" + d.opts.Addr
+}
+
+// Close stops serving and drops the unlocked keys.
+func (d *Daemon) Close() error {
+	d.mu.Lock()
+	d.keys = map[string]daemonKey{}
+	d.mu.Unlock()
+	if nil != d.server {
+		d.server.Close()
+	}
+	if len(d.opts.Socket) > 0 {
+		os.Remove(d.opts.Socket)
+	}
+	return d.audit.Close()
+}
+
+func (d *Daemon) auth(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(d.opts.Token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fn(w, r)
+	}
+}
+
+func (d *Daemon) handleKeys(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	list := []DaemonKey{}
+	for name, k := range d.keys {
+		list = append(list, DaemonKey{name, k.account.Address.String(), k.policy})
+	}
+	d.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+func (d *Daemon) handleSign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req := SignRequest{}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); nil != err {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d.mu.Lock()
+	key, ok := d.keys[req.Key]
+	d.mu.Unlock()
+	if !ok {
+		d.log(AuditEntry{Key: req.Key, Result: "denied: unknown key"})
+		http.Error(w, "unknown key", http.StatusNotFound)
+		return
+	}
+
+	// Check the whole request before signing any of it
+	txs := make([]types.Transaction, len(req.Txns))
+	for i, raw := range req.Txns {
+		if err := msgpack.Decode(raw, &txs[i]); nil != err {
+			http.Error(w, fmt.Sprintf("tx %d: %s", i, err), http.StatusBadRequest)
+			return
+		}
+	}
+	for i, tx := range txs {
+		if err := key.policy.Check(tx); nil != err {
+			d.log(auditTx(req.Key, tx, "denied: "+err.Error()))
+			http.Error(w, fmt.Sprintf("tx %d: %s", i, err), http.StatusForbidden)
+			return
+		}
+	}
+
+	res := SignResponse{}
+	for i, tx := range txs {
+		_, stx, err := crypto.SignTransaction(key.account.PrivateKey, tx)
+		if err != nil {
+			d.log(auditTx(req.Key, tx, "failed: "+err.Error()))
+			http.Error(w, fmt.Sprintf("tx %d: %s", i, err), http.StatusInternalServerError)
+			return
+		}
+		d.log(auditTx(req.Key, tx, "signed"))
+		res.Signed = append(res.Signed, stx)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func auditTx(key string, tx types.Transaction, result string) AuditEntry {
+	e := AuditEntry{
+		Key:    key,
+		TxId:   crypto.TransactionIDString(tx),
+		Type:   tx.Type,
+		Sender: tx.Sender.String(),
+		AppId:  uint64(tx.ApplicationID),
+		Result: result,
+	}
+	switch tx.Type {
+	case types.PaymentTx:
+		e.Receiver, e.Amount = tx.Receiver.String(), uint64(tx.Amount)
+	case types.AssetTransferTx:
+		e.Receiver, e.Amount = tx.AssetReceiver.String(), tx.AssetAmount
+	}
+	return e
+}
+
+func (d *Daemon) log(e AuditEntry) {
+	e.Time = time.Now().UTC()
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.audit.Write(append(line, '\n')); nil != err {
+		fmt.Println(">> audit log failed:", err)
+	}
+}
+
+func checkLoopback(addr string) error {
+	host, _, err := stdnet.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := stdnet.ParseIP(host); nil != ip && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("not a loopback address: %s", addr)
+}
+
+// DaemonSigner signs through a signer daemon, the key never enters the
+// calling process.
+type DaemonSigner struct {
+	Key string
+
+	base   string
+	token  string
+	client *http.Client
+	addr   types.Address
+}
+
+// NewDaemonSigner connects to a daemon endpoint, unix://<path> or
+// http://<host:port>, and looks up the address of the key.
+func NewDaemonSigner(endpoint, token, key string) (DaemonSigner, error) {
+	s := DaemonSigner{Key: key, token: token}
+	timeout := time.Duration(cfg.Timeout()) * time.Second
+	if path := strings.TrimPrefix(endpoint, "unix://"); path != endpoint {
+		s.base = "http://signer"
+		s.client = &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (stdnet.Conn, error) {
+					return (&stdnet.Dialer{}).DialContext(ctx, "unix", path)
+				},
+			},
+		}
+	} else {
+		s.base = strings.TrimSuffix(endpoint, "/")
+		s.client = &http.Client{Timeout: timeout}
+	}
+
+	keys := []DaemonKey{}
+	if err := s.do(http.MethodGet, "/v1/keys", nil, &keys); nil != err {
+		return s, fmt.Errorf("daemon signer: %w", err)
+	}
+	for _, k := range keys {
+		if k.Name == key {
+			addr, err := types.DecodeAddress(k.Address)
+			if err != nil {
+				return s, fmt.Errorf("daemon signer: %s", err)
+			}
+			s.addr = addr
+			return s, nil
+		}
+	}
+	return s, fmt.Errorf("daemon signer: key not unlocked: %s: %w", key, ErrSignerUnavailable)
+}
+
+func (s DaemonSigner) Address() types.Address {
+	return s.addr
+}
+
+func (s DaemonSigner) SignTransaction(tx types.Transaction) ([]byte, error) {
+	out, err := s.SignGroup([]types.Transaction{tx})
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+func (s DaemonSigner) SignGroup(txs []types.Transaction) ([][]byte, error) {
+	req := SignRequest{Key: s.Key}
+	for _, tx := range txs {
+		req.Txns = append(req.Txns, msgpack.Encode(tx))
+	}
+	res := SignResponse{}
+	if err := s.do(http.MethodPost, "/v1/sign", req, &res); nil != err {
+		return nil, fmt.Errorf("daemon sign: %w", err)
+	}
+	if len(res.Signed) != len(txs) {
+		return nil, fmt.Errorf("daemon sign: %d of %d signed", len(res.Signed), len(txs))
+	}
+	for i, raw := range res.Signed {
+		stx := types.SignedTxn{}
+		if err := msgpack.Decode(raw, &stx); nil != err {
+			return nil, fmt.Errorf("daemon sign: decode: %s", err)
+		}
+		if crypto.TransactionIDString(stx.Txn) != crypto.TransactionIDString(txs[i]) {
+			return nil, fmt.Errorf("daemon sign: tx %d: signed a different transaction", i)
+		}
+	}
+	return res.Signed, nil
+}
+
+func (s DaemonSigner) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if nil != in {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.base+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return json.Unmarshal(data, out)
+	case http.StatusForbidden:
+		msg := strings.TrimSuffix(string(bytes.TrimSpace(data)), ": "+ErrPolicyDenied.Error())
+		return fmt.Errorf("%s: %w", msg, ErrPolicyDenied)
+	}
+	return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+}
@@ -0,0 +1,114 @@
+package acc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+func TestKeyPolicyCheck(t *testing.T) {
+	sender := crypto.GenerateAccount().Address
+	allowed := crypto.GenerateAccount().Address
+	other := crypto.GenerateAccount().Address
+
+	pay := func(amount, fee uint64, receiver types.Address) types.Transaction {
+		tx := types.Transaction{Type: types.PaymentTx}
+		tx.Sender = sender
+		tx.Fee = types.MicroAlgos(fee)
+		tx.Receiver = receiver
+		tx.Amount = types.MicroAlgos(amount)
+		return tx
+	}
+	typed := func(t types.TxType) types.Transaction {
+		tx := types.Transaction{Type: t}
+		tx.Sender = sender
+		tx.Fee = 1000
+		return tx
+	}
+	with := func(tx types.Transaction, fn func(*types.Transaction)) types.Transaction {
+		fn(&tx)
+		return tx
+	}
+
+	receivers := KeyPolicy{Receivers: []string{allowed.String()}}
+	tests := []struct {
+		name   string
+		policy KeyPolicy
+		tx     types.Transaction
+		denied bool
+	}{
+		{"open policy", KeyPolicy{}, pay(1e9, 1e6, other), false},
+		{"receiver allowed", receivers, pay(1000, 1000, allowed), false},
+		{"receiver denied", receivers, pay(1000, 1000, other), true},
+
+		{"keyreg denied by default", receivers, typed(types.KeyRegistrationTx), true},
+		{"acfg denied by default", receivers, typed(types.AssetConfigTx), true},
+		{"afrz denied by default", receivers, typed(types.AssetFreezeTx), true},
+		{"type allowed", KeyPolicy{Types: []types.TxType{types.KeyRegistrationTx}}, typed(types.KeyRegistrationTx), false},
+		{"type not listed", KeyPolicy{Types: []types.TxType{types.KeyRegistrationTx}}, pay(0, 1000, other), true},
+
+		{"rekey denied", KeyPolicy{}, with(pay(0, 1000, allowed), func(tx *types.Transaction) {
+			tx.RekeyTo = allowed
+		}), true},
+		{"rekey allowed", KeyPolicy{AllowRekey: true}, with(pay(0, 1000, allowed), func(tx *types.Transaction) {
+			tx.RekeyTo = allowed
+		}), false},
+		{"rekey to other", KeyPolicy{AllowRekey: true, Receivers: receivers.Receivers}, with(pay(0, 1000, allowed), func(tx *types.Transaction) {
+			tx.RekeyTo = other
+		}), true},
+
+		{"close denied", receivers, with(pay(0, 1000, allowed), func(tx *types.Transaction) {
+			tx.CloseRemainderTo = allowed
+		}), true},
+		{"close allowed", KeyPolicy{AllowClose: true, Receivers: receivers.Receivers}, with(pay(0, 1000, allowed), func(tx *types.Transaction) {
+			tx.CloseRemainderTo = allowed
+		}), false},
+		{"close to other", KeyPolicy{AllowClose: true, Receivers: receivers.Receivers}, with(pay(0, 1000, allowed), func(tx *types.Transaction) {
+			tx.CloseRemainderTo = other
+		}), true},
+		{"asset close denied", receivers, with(typed(types.AssetTransferTx), func(tx *types.Transaction) {
+			tx.AssetReceiver = allowed
+			tx.AssetCloseTo = allowed
+		}), true},
+
+		{"acfg addresses allowed", KeyPolicy{Types: []types.TxType{types.AssetConfigTx}, Receivers: receivers.Receivers}, with(typed(types.AssetConfigTx), func(tx *types.Transaction) {
+			tx.AssetParams.Manager = sender
+			tx.AssetParams.Reserve = allowed
+		}), false},
+		{"acfg manager to other", KeyPolicy{Types: []types.TxType{types.AssetConfigTx}, Receivers: receivers.Receivers}, with(typed(types.AssetConfigTx), func(tx *types.Transaction) {
+			tx.AssetParams.Manager = other
+		}), true},
+		{"acfg clawback to other", KeyPolicy{Types: []types.TxType{types.AssetConfigTx}, Receivers: receivers.Receivers}, with(typed(types.AssetConfigTx), func(tx *types.Transaction) {
+			tx.AssetParams.Clawback = other
+		}), true},
+
+		{"default fee cap", receivers, pay(0, DefaultMaxFee+1, allowed), true},
+		{"default fee cap met", receivers, pay(0, DefaultMaxFee, allowed), false},
+		{"max fee", KeyPolicy{MaxFee: 2000}, pay(0, 2001, other), true},
+		{"fee counts to amount", KeyPolicy{MaxAmount: 5000}, pay(4500, 1000, other), true},
+		{"amount with fee", KeyPolicy{MaxAmount: 5000}, pay(4000, 1000, other), false},
+		{"asset amount", KeyPolicy{MaxAssetAmount: 10}, with(typed(types.AssetTransferTx), func(tx *types.Transaction) {
+			tx.AssetReceiver = other
+			tx.AssetAmount = 11
+		}), true},
+		{"app denied", KeyPolicy{AppIds: []uint64{7}}, with(typed(types.ApplicationCallTx), func(tx *types.Transaction) {
+			tx.ApplicationID = 8
+		}), true},
+		{"app allowed", KeyPolicy{AppIds: []uint64{7}}, with(typed(types.ApplicationCallTx), func(tx *types.Transaction) {
+			tx.ApplicationID = 7
+		}), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.policy.Check(test.tx)
+			if test.denied && !errors.Is(err, ErrPolicyDenied) {
+				t.Fatalf("expected %s, got %v", ErrPolicyDenied, err)
+			}
+			if !test.denied && nil != err {
+				t.Fatalf("expected no error, got %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package acc
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+
+	"github.com/algorand/go-algorand-sdk/crypto"
+)
+
+// ErrDevnetOnly is returned when derived accounts are used with a
+// testnet or mainnet target.
+var ErrDevnetOnly = errors.New("only available for devnet")
+
+// deriveDomain separates derived keys from any other use of the seed.
+const deriveDomain = "go-pyteal devnet account"
+
+// DeriveAccount returns the devnet test account of a seed and label,
+// the same pair always gives the same account. The seed is the only
+// secret, these keys must never hold value.
+func DeriveAccount(seed, label string) (crypto.Account, error) {
+	if cfg.Target() != cfg.Devnet {
+		return crypto.Account{}, fmt.Errorf("derive account: %w", ErrDevnetOnly)
+	}
+	if len(seed) == 0 {
+		return crypto.Account{}, fmt.Errorf("derive account: empty seed")
+	}
+	if len(label) == 0 {
+		return crypto.Account{}, fmt.Errorf("derive account: empty label")
+	}
+
+	mac := hmac.New(sha256.New, []byte(seed))
+	mac.Write([]byte(deriveDomain))
+	mac.Write([]byte{0})
+	mac.Write([]byte(label))
+	return crypto.AccountFromPrivateKey(ed25519.NewKeyFromSeed(mac.Sum(nil)))
+}
+
+// DeriveAccountIndex derives the account at an index, it is the same
+// as the label "#<index>".
+func DeriveAccountIndex(seed string, index uint32) (crypto.Account, error) {
+	return DeriveAccount(seed, fmt.Sprintf("#%d", index))
+}
+
+// CreateDerived stores the account derived from the configured seed
+// with the name as label, marked as devnet only. An existing file is
+// kept when it holds the same account, so fixture names always map to
+// the same address.
+func CreateDerived(name, pass string) (crypto.Account, error) {
+	return CreateDerivedWithSeed(name, pass, cfg.DevSeed())
+}
+
+func CreateDerivedWithSeed(name, pass, seed string) (crypto.Account, error) {
+	path := accountPath(name)
+	fmt.Println(":: Create derived account:", path)
+
+	if err := checkName(name); nil != err {
+		return crypto.Account{}, fmt.Errorf("create account: %s", err)
+	}
+	acc, err := DeriveAccount(seed, name)
+	if err != nil {
+		return crypto.Account{}, fmt.Errorf("create account: %w", err)
+	}
+
+	if doesAccountExist(path) {
+		store, err := readKeyStore(path)
+		if err != nil {
+			return crypto.Account{}, fmt.Errorf("create account: %s", err)
+		}
+		if len(store.Addr) == 0 {
+			return crypto.Account{}, fmt.Errorf("create account: file exists without address, upgrade it: %s", path)
+		}
+		if store.Addr != acc.Address.String() {
+			return crypto.Account{}, fmt.Errorf("create account: file exists with another account: %s", path)
+		}
+		if !store.Devnet {
+			// Mark files of older versions as devnet only
+			if _, err := LoadAccountFromFile(pass, path); nil != err {
+				return crypto.Account{}, fmt.Errorf("create account: %w", err)
+			}
+			if err := SaveDevAccountToFile(acc, pass, path); nil != err {
+				return crypto.Account{}, fmt.Errorf("create account: save file : %s", err)
+			}
+		}
+		return acc, nil
+	}
+
+	if err := SaveDevAccountToFile(acc, pass, path); nil != err {
+		return crypto.Account{}, fmt.Errorf("create account: save file : %s", err)
+	}
+	return acc, nil
+}
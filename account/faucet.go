@@ -0,0 +1,247 @@
+package acc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	stdnet "net"
+	"net/http"
+	"sync"
+	"time"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+	net "github.com/vecno-io/go-pyteal/network"
+)
+
+// ErrNoFaucet is returned when the target has no faucet configured.
+var ErrNoFaucet = errors.New("no faucet")
+
+// ErrFaucetLimit is returned when a funding exceeds the faucet policy.
+var ErrFaucetLimit = errors.New("faucet limit")
+
+// Faucet funds an address and returns the confirmed txid.
+type Faucet interface {
+	Fund(address string, amount uint64) (string, error)
+}
+
+// ConfiguredFaucet returns the faucet of the target with its policy,
+// an http dispenser when a url is set, else the devnet genesis wallet.
+func ConfiguredFaucet() (Faucet, error) {
+	setup := cfg.Faucet()
+	switch {
+	case len(setup.Url) > 0:
+		return WithPolicy(NewHttpFaucet(setup.Url, setup.Token), setup), nil
+	case cfg.Target() == cfg.Devnet:
+		return WithPolicy(DevFaucet{}, setup), nil
+	}
+	return nil, fmt.Errorf("faucet: %s: %w", cfg.TargetName(), ErrNoFaucet)
+}
+
+// DevFaucet funds from the devnet genesis wallet.
+type DevFaucet struct{}
+
+func (DevFaucet) Fund(address string, amount uint64) (txid string, err error) {
+	err = net.WithLock(net.LockShared, func() error {
+		txid, err = DevFunding(address, amount)
+		return err
+	})
+	return txid, err
+}
+
+// HttpFaucet funds through a dispenser api, a POST of the request
+// as json that responds with the txid. The txid is confirmed before
+// it is returned.
+type HttpFaucet struct {
+	Url    string
+	Token  string
+	Client *http.Client
+}
+
+// FaucetRequest is the body of a dispenser request.
+type FaucetRequest struct {
+	Address string `json:"address"`
+	Amount  uint64 `json:"amount"`
+}
+
+// FaucetResponse is the body of a dispenser response.
+type FaucetResponse struct {
+	TxId string `json:"txid"`
+}
+
+func NewHttpFaucet(url, token string) HttpFaucet {
+	return HttpFaucet{
+		Url:    url,
+		Token:  token,
+		Client: &http.Client{Timeout: time.Duration(cfg.Timeout()) * time.Second},
+	}
+}
+
+func (f HttpFaucet) Fund(address string, amount uint64) (string, error) {
+	fmt.Println(":: Faucet fund:", address, amount)
+
+	body, err := json.Marshal(FaucetRequest{address, amount})
+	if err != nil {
+		return "", fmt.Errorf("faucet: %s", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, f.Url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("faucet: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(f.Token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+f.Token)
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("faucet: %s", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("faucet: %s", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "", fmt.Errorf("faucet: %s: %w", bytes.TrimSpace(data), ErrFaucetLimit)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("faucet: %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	res := FaucetResponse{}
+	if err := json.Unmarshal(data, &res); nil != err {
+		return "", fmt.Errorf("faucet: decode: %s", err)
+	}
+	if len(res.TxId) == 0 {
+		return "", fmt.Errorf("faucet: decode: no txid in response: %s", bytes.TrimSpace(data))
+	}
+
+	err = net.WithLock(net.LockShared, func() error {
+		cl, err := net.MakeClient()
+		if err != nil {
+			return fmt.Errorf("faucet: make client: %s", err)
+		}
+		opts := net.DefaultSendOptions()
+		if _, err := net.WaitForConfirmation(cl, res.TxId, opts.WaitRounds, context.Background()); nil != err {
+			return fmt.Errorf("faucet: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return res.TxId, nil
+}
+
+// PolicyFaucet applies a funding policy to a faucet.
+type PolicyFaucet struct {
+	Faucet Faucet
+	Policy cfg.FaucetSetup
+
+	mu   sync.Mutex
+	sent []time.Time
+}
+
+func WithPolicy(f Faucet, p cfg.FaucetSetup) *PolicyFaucet {
+	return &PolicyFaucet{Faucet: f, Policy: p}
+}
+
+func (f *PolicyFaucet) Fund(address string, amount uint64) (string, error) {
+	if f.Policy.MaxAmount > 0 && amount > f.Policy.MaxAmount {
+		return "", fmt.Errorf("faucet: amount %d above %d: %w", amount, f.Policy.MaxAmount, ErrFaucetLimit)
+	}
+	if err := f.take(); nil != err {
+		return "", err
+	}
+	return f.Faucet.Fund(address, amount)
+}
+
+// take records a request, failed fundings count as well.
+func (f *PolicyFaucet) take() error {
+	if f.Policy.Requests == 0 {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	window := time.Duration(f.Policy.Window) * time.Second
+	recent := f.sent[:0]
+	for _, t := range f.sent {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	f.sent = recent
+	if len(f.sent) >= int(f.Policy.Requests) {
+		return fmt.Errorf("faucet: %d requests per %s: %w", f.Policy.Requests, window, ErrFaucetLimit)
+	}
+	f.sent = append(f.sent, now)
+	return nil
+}
+
+// MockDispenser is a local dispenser api for tests, it serves the api
+// used by HttpFaucet on localhost and funds through another faucet.
+type MockDispenser struct {
+	Url   string
+	Token string
+
+	faucet Faucet
+	server *http.Server
+}
+
+// NewMockDispenser starts a dispenser on a free localhost port.
+func NewMockDispenser(f Faucet, token string) (*MockDispenser, error) {
+	ln, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("mock dispenser: %s", err)
+	}
+	d := &MockDispenser{
+		Url:    fmt.Sprintf("http://%s/dispense", ln.Addr()),
+		Token:  token,
+		faucet: f,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dispense", d.dispense)
+	d.server = &http.Server{Handler: mux}
+	go d.server.Serve(ln)
+	return d, nil
+}
+
+// Faucet returns an http faucet for the dispenser.
+func (d *MockDispenser) Faucet() HttpFaucet {
+	return NewHttpFaucet(d.Url, d.Token)
+}
+
+func (d *MockDispenser) Close() error {
+	return d.server.Close()
+}
+
+func (d *MockDispenser) dispense(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(d.Token) > 0 && r.Header.Get("Authorization") != "Bearer "+d.Token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	req := FaucetRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); nil != err {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	txid, err := d.faucet.Fund(req.Address, req.Amount)
+	if errors.Is(err, ErrFaucetLimit) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(FaucetResponse{TxId: txid})
+}
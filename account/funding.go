@@ -0,0 +1,160 @@
+package acc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	net "github.com/vecno-io/go-pyteal/network"
+
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/future"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// ErrInsufficientFunds is returned when the dispenser can not cover
+// the funding and the fees.
+var ErrInsufficientFunds = errors.New("insufficient dispenser funds")
+
+// Funding is an amount for an address, for top ups the target balance.
+type Funding struct {
+	Address string
+	Amount  uint64
+}
+
+// DevFunding sends amount from the devnet dispenser and waits for the
+// confirmation, it returns the txid.
+func DevFunding(address string, amount uint64) (string, error) {
+	return DevFundingWithParams(address, amount, net.ParamsOptions{})
+}
+
+func DevFundingWithParams(address string, amount uint64, opts net.ParamsOptions) (string, error) {
+	ids, err := DevFundingMany([]Funding{{address, amount}}, opts)
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// DevFundingMany funds all addresses in a single atomic group, either
+// all payments are confirmed or none is. It returns the txids in order.
+func DevFundingMany(targets []Funding, opts net.ParamsOptions) ([]string, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	if len(targets) > maxGroupSize {
+		return nil, fmt.Errorf("funding: %d payments, at most %d fit a group", len(targets), maxGroupSize)
+	}
+	for _, t := range targets {
+		fmt.Println(":: Fund account:", t.Address, t.Amount)
+	}
+
+	dispenser, err := DevDispenser()
+	if err != nil {
+		return nil, fmt.Errorf("funding: %w", err)
+	}
+	params, err := net.MakeTxnParamsWith(opts)
+	if err != nil {
+		return nil, fmt.Errorf("funding: params: %s", err)
+	}
+
+	from := dispenser.Address().String()
+	txs := make([]types.Transaction, len(targets))
+	total := uint64(0)
+	for i, t := range targets {
+		tx, err := future.MakePaymentTxn(from, t.Address, t.Amount, nil, "", params)
+		if err != nil {
+			return nil, fmt.Errorf("funding: make tx: %s", err)
+		}
+		txs[i] = net.ApplyMinFee(tx, params)
+		total += t.Amount + uint64(txs[i].Fee)
+	}
+	if err := checkDispenser(from, total); nil != err {
+		return nil, fmt.Errorf("funding: %w", err)
+	}
+
+	if len(txs) > 1 {
+		gid, err := crypto.ComputeGroupID(txs)
+		if err != nil {
+			return nil, fmt.Errorf("funding: group: %s", err)
+		}
+		for i := range txs {
+			txs[i].Group = gid
+		}
+	}
+	signed, err := dispenser.SignGroup(txs)
+	if err != nil {
+		return nil, fmt.Errorf("funding: sign: %s", err)
+	}
+	raw := []byte{}
+	for _, stx := range signed {
+		raw = append(raw, stx...)
+	}
+	if _, err := net.Send(raw, net.DefaultSendOptions()); nil != err {
+		return nil, fmt.Errorf("funding: %w", err)
+	}
+
+	ids := make([]string, len(txs))
+	for i, tx := range txs {
+		ids[i] = crypto.TransactionIDString(tx)
+	}
+	return ids, nil
+}
+
+// DevTopUp funds an address up to the target balance, nothing is sent
+// and the txid is empty when the balance is already reached.
+func DevTopUp(address string, target uint64) (string, error) {
+	return DevTopUpWithParams(address, target, net.ParamsOptions{})
+}
+
+func DevTopUpWithParams(address string, target uint64, opts net.ParamsOptions) (string, error) {
+	ids, err := DevTopUpMany([]Funding{{address, target}}, opts)
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// DevTopUpMany funds all addresses below their target balance in a
+// single atomic group, the txids of funded addresses are set.
+func DevTopUpMany(targets []Funding, opts net.ParamsOptions) ([]string, error) {
+	cl, err := net.MakeClient()
+	if err != nil {
+		return nil, fmt.Errorf("top up: make client: %s", err)
+	}
+
+	index := []int{}
+	funding := []Funding{}
+	for i, t := range targets {
+		info, err := cl.AccountInformation(t.Address).Do(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("top up: %s: %s", t.Address, err)
+		}
+		if info.Amount >= t.Amount {
+			continue
+		}
+		index = append(index, i)
+		funding = append(funding, Funding{t.Address, t.Amount - info.Amount})
+	}
+
+	ids := make([]string, len(targets))
+	sent, err := DevFundingMany(funding, opts)
+	if err != nil {
+		return nil, fmt.Errorf("top up: %w", err)
+	}
+	for i, id := range sent {
+		ids[index[i]] = id
+	}
+	return ids, nil
+}
+
+func checkDispenser(address string, total uint64) error {
+	v, err := View(address)
+	if err != nil {
+		return fmt.Errorf("dispenser: %s", err)
+	}
+	if v.Spendable < total {
+		return fmt.Errorf("%d spendable, %d needed: %w", v.Spendable, total, ErrInsufficientFunds)
+	}
+	return nil
+}
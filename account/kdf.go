@@ -0,0 +1,102 @@
+package acc
+
+import (
+	"fmt"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KdfScrypt and KdfArgon2id are the supported key derivation methods.
+const (
+	KdfScrypt   = "scrypt"
+	KdfArgon2id = "argon2id"
+)
+
+// KdfProfile holds the key derivation values of a profile, with the
+// same meaning as in KeyInfo.
+type KdfProfile struct {
+	T string
+	N uint64
+	R uint64
+	P uint64
+}
+
+// KdfProfiles are the named profiles, fast is meant for throwaway test
+// keys and matches the values used by older keystores.
+var KdfProfiles = map[string]KdfProfile{
+	"fast":     {T: KdfScrypt, N: 2048, R: 8, P: 1},
+	"standard": {T: KdfArgon2id, N: 64 * 1024, R: 3, P: 4},
+	"paranoid": {T: KdfArgon2id, N: 256 * 1024, R: 4, P: 4},
+}
+
+// ConfiguredKdf returns the profile chosen in the config.
+func ConfiguredKdf() (KdfProfile, error) {
+	return KdfProfileByName(cfg.Kdf())
+}
+
+func KdfProfileByName(name string) (KdfProfile, error) {
+	p, ok := KdfProfiles[name]
+	if !ok {
+		return KdfProfile{}, fmt.Errorf("unknown kdf profile: %s", name)
+	}
+	return p, nil
+}
+
+// Cost is a rough estimate of the work to derive a key in bytes of
+// memory touched, it is used to compare profiles of both methods.
+func (p KdfProfile) Cost() uint64 {
+	switch p.T {
+	case KdfScrypt:
+		return 2 * 128 * p.N * p.R * p.P
+	case KdfArgon2id:
+		return 1024 * p.N * p.R
+	}
+	return 0
+}
+
+func (p KdfProfile) info(salt string) KeyInfo {
+	return KeyInfo{
+		T: p.T,
+		S: salt,
+		N: p.N,
+		R: p.R,
+		P: p.P,
+		L: 32,
+	}
+}
+
+// profile returns the profile of stored key info, an empty T is scrypt.
+func (k KeyInfo) profile() KdfProfile {
+	p := KdfProfile{T: k.T, N: k.N, R: k.R, P: k.P}
+	if len(p.T) == 0 {
+		p.T = KdfScrypt
+	}
+	return p
+}
+
+// deriveKey derives the key, version 1 files swapped the scrypt block
+// size and parallelization.
+func deriveKey(pass string, salt []byte, k KeyInfo, ver uint64) ([]byte, error) {
+	p := k.profile()
+	switch p.T {
+	case KdfScrypt:
+		r, par := int(p.R), int(p.P)
+		if ver <= 1 {
+			r, par = par, r
+		}
+		key, err := scrypt.Key([]byte(pass), salt, int(p.N), r, par, int(k.L))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create scrypt key: %s", err)
+		}
+		return key, nil
+	case KdfArgon2id:
+		if p.N == 0 || p.R == 0 || p.P == 0 || p.P > 255 || p.N > 1<<32-1 || p.R > 1<<32-1 {
+			return nil, fmt.Errorf("invalid argon2id values")
+		}
+		return argon2.IDKey([]byte(pass), salt, uint32(p.R), uint32(p.N), uint8(p.P), uint32(k.L)), nil
+	}
+	return nil, fmt.Errorf("unsupported key derivation: %s", p.T)
+}
@@ -0,0 +1,91 @@
+package acc
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+
+	"github.com/algorand/go-algorand-sdk/crypto"
+)
+
+func TestKdfProfilesRoundTrip(t *testing.T) {
+	for _, name := range []string{"fast", "standard", "paranoid"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			profile, err := KdfProfileByName(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			acc := crypto.GenerateAccount()
+			path := filepath.Join(t.TempDir(), "test.acc")
+			if err := SaveAccountToFileWith(acc, "pass", path, profile); nil != err {
+				t.Fatalf("save: %s", err)
+			}
+
+			store := KeyStore{}
+			if err := readJson(path, &store); nil != err {
+				t.Fatal(err)
+			}
+			if store.Key.profile() != profile || store.Type.K != profile.T {
+				t.Fatalf("stored kdf: expected %+v, got %+v (%s)", profile, store.Key, store.Type.K)
+			}
+
+			loaded, err := LoadAccountFromFile("pass", path)
+			if err != nil {
+				t.Fatalf("load: %s", err)
+			}
+			if !bytes.Equal(loaded.PrivateKey, acc.PrivateKey) {
+				t.Fatal("private key mismatch")
+			}
+			if _, err := LoadAccountFromFile("wrong", path); !errors.Is(err, ErrWrongPassphrase) {
+				t.Fatalf("wrong passphrase: expected %s, got %v", ErrWrongPassphrase, err)
+			}
+		})
+	}
+}
+
+func TestKdfRehashOnLoad(t *testing.T) {
+	acc := crypto.GenerateAccount()
+	path := filepath.Join(t.TempDir(), "test.acc")
+	if err := SaveAccountToFileWith(acc, "pass", path, KdfProfiles["fast"]); nil != err {
+		t.Fatalf("save: %s", err)
+	}
+
+	if err := cfg.OnCreate(cfg.Setup{Target: "devnet", Kdf: "standard", Rehash: true}); nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cfg.OnCreate(cfg.Setup{Target: "devnet"})
+	})
+
+	loaded, err := LoadAccountFromFile("pass", path)
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	if !bytes.Equal(loaded.PrivateKey, acc.PrivateKey) {
+		t.Fatal("private key mismatch")
+	}
+	store := KeyStore{}
+	if err := readJson(path, &store); nil != err {
+		t.Fatal(err)
+	}
+	if store.Key.profile() != KdfProfiles["standard"] {
+		t.Fatalf("not rehashed: %+v", store.Key)
+	}
+
+	// The stronger file is left as is and still loads
+	if _, err := LoadAccountFromFile("pass", path); nil != err {
+		t.Fatalf("load rehashed: %s", err)
+	}
+
+	// A failed rehash fails the load
+	if err := cfg.OnCreate(cfg.Setup{Target: "devnet", Kdf: "unknown", Rehash: true}); nil != err {
+		t.Fatal(err)
+	}
+	if _, err := LoadAccountFromFile("pass", path); nil == err {
+		t.Fatal("failed rehash not reported")
+	}
+}
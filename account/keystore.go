@@ -7,10 +7,12 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
-	"golang.org/x/crypto/scrypt"
+	cfg "github.com/vecno-io/go-pyteal/config"
 
 	uuid "github.com/satori/go.uuid"
 
@@ -18,16 +20,30 @@ import (
 	"github.com/algorand/go-algorand-sdk/mnemonic"
 )
 
-// KeyId is the key derivation method.
-const KeyId = "scrypt"
+// KeyId is the default key derivation method.
+const KeyId = KdfScrypt
 
 // DataId is the encryption method used.
-const DataId = "aes-256-cbc"
+const DataId = "aes-256-gcm"
 
-// KeyStore holds values to store account keys.
+// DataIdV1 is the encryption method of version 1 files, it
+// is not authenticated and only supported for loading.
+const DataIdV1 = "aes-256-cbc"
+
+// KeyStoreVersion is the version of newly saved files.
+const KeyStoreVersion = 2
+
+// ErrWrongPassphrase is returned when a keystore fails to decrypt,
+// for version 2 files this includes tampered data.
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+
+// KeyStore holds values to store account keys, Devnet marks keys that
+// must only be used with a devnet target.
 type KeyStore struct {
-	Id  string `json:"id"`
-	Ver uint64 `json:"ver"`
+	Id     string `json:"id"`
+	Ver    uint64 `json:"ver"`
+	Addr   string `json:"addr,omitempty"`
+	Devnet bool   `json:"devnet,omitempty"`
 
 	Key  KeyInfo `json:"key"`
 	Data KeyData `json:"data"`
@@ -35,10 +51,11 @@ type KeyStore struct {
 }
 
 // KeyInfo holds the key derivation values.
+// T scrypt | argon2id (empty is scrypt)
 // S   32 (salt)
-// N 2048 (cpu cost)
-// R    8 (blocksize)
-// P    1 (parallelize)
+// N 2048 (cpu cost, argon2id: memory in KiB)
+// R    8 (blocksize, argon2id: passes)
+// P    1 (parallelize, argon2id: threads)
 // l   32 (dklen)
 type KeyInfo struct {
 	T string `json:"t"`
@@ -49,7 +66,8 @@ type KeyInfo struct {
 	L uint64 `json:"l"`
 }
 
-// KeyData holds the encrypted data and the initial vector.
+// KeyData holds the encrypted data and the initial vector,
+// or the nonce for version 2 files.
 type KeyData struct {
 	T string `json:"t"`
 	S string `json:"s"`
@@ -63,60 +81,64 @@ type KeyType struct {
 }
 
 func SaveAccountToFile(acc crypto.Account, pass, path string) error {
-	iv := make([]byte, aes.BlockSize)
-	if _, err := rand.Read(iv); err != nil {
-		return fmt.Errorf("failed to create random iv: %s", err)
+	return saveConfigured(acc, pass, path, false)
+}
+
+// SaveDevAccountToFile saves an account marked as devnet only, it
+// fails to load with other targets.
+func SaveDevAccountToFile(acc crypto.Account, pass, path string) error {
+	return saveConfigured(acc, pass, path, true)
+}
+
+func SaveAccountToFileWith(acc crypto.Account, pass, path string, profile KdfProfile) error {
+	return saveAccountFile(acc, pass, path, profile, false)
+}
+
+func saveConfigured(acc crypto.Account, pass, path string, devnet bool) error {
+	profile, err := ConfiguredKdf()
+	if err != nil {
+		return err
 	}
+	return saveAccountFile(acc, pass, path, profile, devnet)
+}
+
+func saveAccountFile(acc crypto.Account, pass, path string, profile KdfProfile, devnet bool) error {
 	salt := make([]byte, 32)
 	if _, err := rand.Read(salt); err != nil {
 		return fmt.Errorf("failed to create random salt: %s", err)
 	}
 
-	key, err := scrypt.Key([]byte(pass), salt, 2048, 1, 8, 32)
+	info := profile.info(hex.EncodeToString(salt))
+	key, err := deriveKey(pass, salt, info, KeyStoreVersion)
 	if err != nil {
-		return fmt.Errorf("failed to create scrypt key: %s", err)
+		return err
 	}
 	data, err := mnemonic.FromPrivateKey(acc.PrivateKey)
 	if err != nil {
-		return fmt.Errorf("failed to create random salt: %s", err)
-	}
-
-	encoded, err := aesEncrypt(data, key, iv, aes.BlockSize)
-	if err != nil {
-		return fmt.Errorf("failed to create encrypt: %s", err)
+		return fmt.Errorf("failed to create mnemonic: %s", err)
 	}
 
-	out, err := json.MarshalIndent(KeyStore{
-		Id:  uuid.NewV4().String(),
-		Ver: 1,
-		Key: KeyInfo{
-			S: hex.EncodeToString(salt),
-			N: 2048,
-			R: 8,
-			P: 1,
-			L: 32,
-		},
-		Data: KeyData{
-			S: hex.EncodeToString(iv),
-			D: encoded,
-		},
+	store := KeyStore{
+		Id:     uuid.NewV4().String(),
+		Ver:    KeyStoreVersion,
+		Addr:   acc.Address.String(),
+		Devnet: devnet,
+		Key:    info,
 		Type: KeyType{
-			K: KeyId,
+			K: info.T,
 			D: DataId,
 		},
-	}, "", "  ")
+	}
+	store.Data, err = gcmEncrypt(data, key, store.additionalData())
 	if err != nil {
-		return fmt.Errorf("failed to marshal json: %s", err)
+		return fmt.Errorf("failed to create encrypt: %s", err)
 	}
 
-	fl, err := os.Create(path)
+	out, err := json.MarshalIndent(store, "", "  ")
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to marshal json: %s", err)
 	}
-	defer fl.Close()
-	fl.Write(out)
-
-	return nil
+	return writeFileAtomic(path, out)
 }
 
 func LoadAccountFromFile(pass, path string) (crypto.Account, error) {
@@ -129,59 +151,166 @@ func LoadAccountFromFile(pass, path string) (crypto.Account, error) {
 	if err = json.Unmarshal(in, &store); err != nil {
 		return crypto.Account{}, err
 	}
-	if store.Type.K != KeyId {
+	if store.Type.K != store.Key.profile().T {
 		return crypto.Account{}, fmt.Errorf("unsupported key type: %s", store.Type.K)
 	}
-	if store.Type.D != DataId {
-		return crypto.Account{}, fmt.Errorf("unsupported data type: %s", store.Type.D)
+	switch {
+	case store.Ver <= 1 && store.Type.D == DataIdV1:
+	case store.Ver == 2 && store.Type.D == DataId:
+	default:
+		return crypto.Account{}, fmt.Errorf("unsupported data type: %s (version %d)", store.Type.D, store.Ver)
 	}
 
-	iv, err := hex.DecodeString(store.Data.S)
-	if err != nil {
-		return crypto.Account{}, err
-	}
 	salt, err := hex.DecodeString(store.Key.S)
 	if err != nil {
 		return crypto.Account{}, err
 	}
 
-	key, err := scrypt.Key(
-		[]byte(pass), salt, int(store.Key.N),
-		int(store.Key.P), int(store.Key.R), int(store.Key.L),
-	)
+	key, err := deriveKey(pass, salt, store.Key, store.Ver)
 	if err != nil {
-		return crypto.Account{}, fmt.Errorf("failed to create scrypt key: %s", err)
+		return crypto.Account{}, err
 	}
 
-	data, err := aesDecrypt(store.Data.D, key, iv)
-	if err != nil {
-		return crypto.Account{}, fmt.Errorf("failed to create encrypt: %s", err)
+	var data string
+	if store.Type.D == DataIdV1 {
+		iv, err := hex.DecodeString(store.Data.S)
+		if err != nil {
+			return crypto.Account{}, err
+		}
+		data, err = aesDecrypt(store.Data.D, key, iv)
+		if err != nil {
+			return crypto.Account{}, fmt.Errorf("failed to decrypt: %w", err)
+		}
+	} else {
+		data, err = gcmDecrypt(store.Data, key, store.additionalData())
+		if err != nil {
+			return crypto.Account{}, fmt.Errorf("failed to decrypt: %w", err)
+		}
 	}
 
+	// Version 1 has no mac, the mnemonic checksum detects a wrong key
 	priv, err := mnemonic.ToPrivateKey(data)
 	if err != nil {
+		if store.Type.D == DataIdV1 {
+			return crypto.Account{}, fmt.Errorf("failed to recover key: %w", ErrWrongPassphrase)
+		}
 		return crypto.Account{}, fmt.Errorf("failed to recover key: %s", err)
 	}
 	acc, err := crypto.AccountFromPrivateKey(priv)
 	if err != nil {
 		return crypto.Account{}, fmt.Errorf("failed to recover account: %s", err)
 	}
+	if len(store.Addr) > 0 && store.Addr != acc.Address.String() {
+		return crypto.Account{}, fmt.Errorf("failed to recover account: address mismatch: %s", store.Addr)
+	}
+	if store.Devnet && cfg.Target() != cfg.Devnet {
+		return crypto.Account{}, fmt.Errorf("devnet account %s: %w", store.Addr, ErrDevnetOnly)
+	}
+
+	if cfg.Rehash() {
+		if err := rehashAccountFile(acc, pass, path, store); nil != err {
+			return crypto.Account{}, fmt.Errorf("rehash keystore: %s", err)
+		}
+	}
 	return acc, nil
 }
 
-func aesEncrypt(data string, key, iv []byte, blockSize int) (string, error) {
-	plainData := addPadding([]byte(data), blockSize, len(data))
+// rehashAccountFile saves the account again when its key derivation
+// is weaker than the configured profile.
+func rehashAccountFile(acc crypto.Account, pass, path string, store KeyStore) error {
+	profile, err := ConfiguredKdf()
+	if err != nil {
+		return err
+	}
+	if store.Key.profile().Cost() >= profile.Cost() {
+		return nil
+	}
+	fmt.Println(">> rehash keystore:", path)
+	return saveAccountFile(acc, pass, path, profile, store.Devnet)
+}
+
+// UpgradeAccountFile re-encrypts a version 1 file in place with the
+// current version, newer files are left as is.
+func UpgradeAccountFile(pass, path string) (bool, error) {
+	in, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	store := KeyStore{}
+	if err = json.Unmarshal(in, &store); err != nil {
+		return false, err
+	}
+	if store.Ver >= KeyStoreVersion {
+		return false, nil
+	}
+
+	acc, err := LoadAccountFromFile(pass, path)
+	if err != nil {
+		return false, err
+	}
+	if err := SaveAccountToFile(acc, pass, path); nil != err {
+		return false, err
+	}
+	return true, nil
+}
+
+// additionalData binds the plain fields to the encrypted data, the
+// devnet mark is only added when set so older files stay valid.
+func (s KeyStore) additionalData() []byte {
+	if s.Devnet {
+		return []byte(fmt.Sprintf("%s:%d:%s:devnet", s.Id, s.Ver, s.Addr))
+	}
+	return []byte(fmt.Sprintf("%s:%d:%s", s.Id, s.Ver, s.Addr))
+}
 
+func gcmEncrypt(data string, key, ad []byte) (KeyData, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", fmt.Errorf("faild to set key: %s", err)
+		return KeyData{}, fmt.Errorf("faild to set key: %s", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return KeyData{}, fmt.Errorf("faild to set mode: %s", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return KeyData{}, fmt.Errorf("failed to create random nonce: %s", err)
 	}
 
-	cipherData := make([]byte, len(plainData))
-	mode := cipher.NewCBCEncrypter(block, iv)
-	mode.CryptBlocks(cipherData, plainData)
+	cipherData := gcm.Seal(nil, nonce, []byte(data), ad)
+	return KeyData{
+		S: hex.EncodeToString(nonce),
+		D: hex.EncodeToString(cipherData),
+	}, nil
+}
+
+func gcmDecrypt(data KeyData, key, ad []byte) (string, error) {
+	nonce, err := hex.DecodeString(data.S)
+	if err != nil {
+		return "", fmt.Errorf("faild to decode nonce: %s", err)
+	}
+	cipherData, err := hex.DecodeString(data.D)
+	if err != nil {
+		return "", fmt.Errorf("faild to decode data: %s", err)
+	}
 
-	return hex.EncodeToString(cipherData), nil
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", fmt.Errorf("faild to set key: %s", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", fmt.Errorf("faild to set mode: %s", err)
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return "", fmt.Errorf("invalid nonce size: %d", len(nonce))
+	}
+
+	plainData, err := gcm.Open(nil, nonce, cipherData, ad)
+	if err != nil {
+		return "", ErrWrongPassphrase
+	}
+	return string(plainData), nil
 }
 
 func aesDecrypt(data string, key, iv []byte) (string, error) {
@@ -189,6 +318,12 @@ func aesDecrypt(data string, key, iv []byte) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("faild to decode data: %s", err)
 	}
+	if len(cipherData) == 0 || len(cipherData)%aes.BlockSize != 0 {
+		return "", fmt.Errorf("invalid data size: %d", len(cipherData))
+	}
+	if len(iv) != aes.BlockSize {
+		return "", fmt.Errorf("invalid iv size: %d", len(iv))
+	}
 
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -199,17 +334,44 @@ func aesDecrypt(data string, key, iv []byte) (string, error) {
 	mode := cipher.NewCBCDecrypter(block, iv)
 	mode.CryptBlocks(plainData, cipherData)
 
-	return string(clrPadding(plainData)), nil
+	plainData, err = clrPadding(plainData, aes.BlockSize)
+	if err != nil {
+		return "", err
+	}
+	return string(plainData), nil
 }
 
-// clrPadding removes padding from data following PKCS#7
-func clrPadding(data []byte) []byte {
+// clrPadding removes padding from data following PKCS#7, invalid
+// padding is the result of decrypting with the wrong key.
+func clrPadding(data []byte, size int) ([]byte, error) {
 	length := int(data[len(data)-1])
-	return data[:(len(data) - length)]
+	if length == 0 || length > size || length > len(data) {
+		return nil, ErrWrongPassphrase
+	}
+	if !bytes.Equal(data[len(data)-length:], bytes.Repeat([]byte{byte(length)}, length)) {
+		return nil, ErrWrongPassphrase
+	}
+	return data[:(len(data) - length)], nil
 }
 
-// addPadding padds the data following the PKCS#7 standard
-func addPadding(data []byte, size int, after int) []byte {
-	padding := (size - len(data)%size)
-	return append(data, bytes.Repeat([]byte{byte(padding)}, padding)...)
+// writeFileAtomic replaces a file without leaving a partial file behind.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); nil != err {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); nil != err {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); nil != err {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
 }
@@ -0,0 +1,211 @@
+package acc
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/algorand/go-algorand-sdk/crypto"
+)
+
+// testdata/v1.acc is written by the version 1 SaveAccountToFile, its
+// key was derived with r and p swapped.
+const (
+	v1Pass    = "fixture pass"
+	v1Address = "WRDIQ2HR3IUPTE3YX2ANAKZJYDVIEV7O2GS5G7LVTCPNCV726KL55M76JY"
+)
+
+func TestLoadVersion1(t *testing.T) {
+	acc, err := LoadAccountFromFile(v1Pass, filepath.Join("testdata", "v1.acc"))
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	if acc.Address.String() != v1Address {
+		t.Fatalf("address: expected %s, got %s", v1Address, acc.Address)
+	}
+}
+
+func TestLoadVersion1WrongPassphrase(t *testing.T) {
+	_, err := LoadAccountFromFile("wrong pass", filepath.Join("testdata", "v1.acc"))
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected %s, got %v", ErrWrongPassphrase, err)
+	}
+}
+
+// A wrong key can decrypt to valid padding, the mnemonic checksum
+// has to catch it.
+func TestLoadVersion1BadChecksum(t *testing.T) {
+	store := KeyStore{}
+	if err := readJson(filepath.Join("testdata", "v1.acc"), &store); nil != err {
+		t.Fatal(err)
+	}
+	salt, _ := hex.DecodeString(store.Key.S)
+	iv, _ := hex.DecodeString(store.Data.S)
+	key, err := scrypt.Key([]byte(v1Pass), salt, 2048, 1, 8, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	words := strings.TrimSpace(strings.Repeat("abandon ", 25))
+	store.Data.D = cbcEncrypt(t, words, key, iv)
+	path := writeJson(t, store)
+
+	_, err = LoadAccountFromFile(v1Pass, path)
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected %s, got %v", ErrWrongPassphrase, err)
+	}
+}
+
+func TestSaveLoadVersion2(t *testing.T) {
+	acc, path := saveVersion2(t)
+
+	store := KeyStore{}
+	if err := readJson(path, &store); nil != err {
+		t.Fatal(err)
+	}
+	if store.Ver != KeyStoreVersion || store.Type.D != DataId || store.Addr != acc.Address.String() {
+		t.Fatalf("unexpected keystore: %+v", store)
+	}
+
+	loaded, err := LoadAccountFromFile("pass", path)
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	if !bytes.Equal(loaded.PrivateKey, acc.PrivateKey) {
+		t.Fatalf("private key mismatch")
+	}
+}
+
+func TestLoadVersion2WrongPassphrase(t *testing.T) {
+	_, path := saveVersion2(t)
+
+	_, err := LoadAccountFromFile("wrong pass", path)
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected %s, got %v", ErrWrongPassphrase, err)
+	}
+}
+
+func TestLoadVersion2Tampered(t *testing.T) {
+	tamper := map[string]func(*KeyStore){
+		"address": func(s *KeyStore) {
+			s.Addr = crypto.GenerateAccount().Address.String()
+		},
+		"ciphertext": func(s *KeyStore) {
+			data, _ := hex.DecodeString(s.Data.D)
+			data[0] ^= 0x01
+			s.Data.D = hex.EncodeToString(data)
+		},
+		"id": func(s *KeyStore) {
+			s.Id = "00000000-0000-0000-0000-000000000000"
+		},
+	}
+	for name, fn := range tamper {
+		t.Run(name, func(t *testing.T) {
+			_, path := saveVersion2(t)
+			store := KeyStore{}
+			if err := readJson(path, &store); nil != err {
+				t.Fatal(err)
+			}
+			fn(&store)
+			path = writeJson(t, store)
+
+			_, err := LoadAccountFromFile("pass", path)
+			if !errors.Is(err, ErrWrongPassphrase) {
+				t.Fatalf("expected %s, got %v", ErrWrongPassphrase, err)
+			}
+		})
+	}
+}
+
+func saveVersion2(t *testing.T) (crypto.Account, string) {
+	t.Helper()
+	acc := crypto.GenerateAccount()
+	path := filepath.Join(t.TempDir(), "v2.acc")
+	if err := SaveAccountToFileWith(acc, "pass", path, KdfProfiles["fast"]); nil != err {
+		t.Fatalf("save: %s", err)
+	}
+	return acc, path
+}
+
+func readJson(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJson(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "store.acc")
+	if err := os.WriteFile(path, data, 0600); nil != err {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func cbcEncrypt(t *testing.T, data string, key, iv []byte) string {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pad := aes.BlockSize - len(data)%aes.BlockSize
+	plain := append([]byte(data), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	out := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, plain)
+	return hex.EncodeToString(out)
+}
+
+func TestDevnetKeyStore(t *testing.T) {
+	setTarget(t, "devnet")
+	acc := crypto.GenerateAccount()
+	path := filepath.Join(t.TempDir(), "dev.acc")
+	if err := SaveDevAccountToFile(acc, "pass", path); nil != err {
+		t.Fatalf("save: %s", err)
+	}
+	if _, err := LoadAccountFromFile("pass", path); nil != err {
+		t.Fatalf("load on devnet: %s", err)
+	}
+
+	setTarget(t, "testnet")
+	if _, err := LoadAccountFromFile("pass", path); !errors.Is(err, ErrDevnetOnly) {
+		t.Fatalf("expected %s, got %v", ErrDevnetOnly, err)
+	}
+
+	// The mark is authenticated, removing it fails to decrypt
+	store := KeyStore{}
+	if err := readJson(path, &store); nil != err {
+		t.Fatal(err)
+	}
+	store.Devnet = false
+	if _, err := LoadAccountFromFile("pass", writeJson(t, store)); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected %s, got %v", ErrWrongPassphrase, err)
+	}
+}
+
+// setTarget switches the config target until the test ends.
+func setTarget(t *testing.T, target string) {
+	t.Helper()
+	if err := cfg.OnCreate(cfg.Setup{Target: target}); nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cfg.OnCreate(cfg.Setup{Target: "devnet"})
+	})
+}
@@ -0,0 +1,255 @@
+package acc
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/mnemonic"
+)
+
+// StoredAccount describes a keystore file without decrypting it, the
+// address is empty for files saved before it was stored.
+type StoredAccount struct {
+	Name    string
+	Path    string
+	Address string
+	Version uint64
+	Kdf     string
+	Devnet  bool
+}
+
+func accountsPath() string {
+	return fmt.Sprintf("%s/accounts", cfg.AssetPath())
+}
+
+func accountPath(name string) string {
+	return fmt.Sprintf("%s/%s.acc", accountsPath(), name)
+}
+
+func checkName(name string) error {
+	if len(name) == 0 || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid account name: %q", name)
+	}
+	return nil
+}
+
+// List returns the stored accounts sorted by name.
+func List() ([]StoredAccount, error) {
+	files, err := filepath.Glob(filepath.Join(accountsPath(), "*.acc"))
+	if err != nil {
+		return nil, fmt.Errorf("list accounts: %s", err)
+	}
+	sort.Strings(files)
+
+	list := []StoredAccount{}
+	for _, file := range files {
+		store, err := readKeyStore(file)
+		if err != nil {
+			return nil, fmt.Errorf("list accounts: %s: %s", file, err)
+		}
+		list = append(list, StoredAccount{
+			Name:    strings.TrimSuffix(filepath.Base(file), ".acc"),
+			Path:    file,
+			Address: store.Addr,
+			Version: store.Ver,
+			Kdf:     store.Key.profile().T,
+			Devnet:  store.Devnet,
+		})
+	}
+	return list, nil
+}
+
+// Find returns the stored account with the given address.
+func Find(address string) (StoredAccount, error) {
+	list, err := List()
+	if err != nil {
+		return StoredAccount{}, err
+	}
+	for _, a := range list {
+		if a.Address == address {
+			return a, nil
+		}
+	}
+	return StoredAccount{}, fmt.Errorf("find account: not stored: %s", address)
+}
+
+// Rename moves an account to a new name, a backup is kept.
+func Rename(name, newName string) error {
+	fmt.Println(":: Rename account:", name, "to", newName)
+
+	for _, n := range []string{name, newName} {
+		if err := checkName(n); nil != err {
+			return fmt.Errorf("rename account: %s", err)
+		}
+	}
+	path, newPath := accountPath(name), accountPath(newName)
+	if !doesAccountExist(path) {
+		return fmt.Errorf("rename account: not found: %s", path)
+	}
+	if doesAccountExist(newPath) {
+		return fmt.Errorf("rename account: file exists: %s", newPath)
+	}
+	if _, err := backupAccount(name); nil != err {
+		return fmt.Errorf("rename account: backup: %s", err)
+	}
+	if err := os.Rename(path, newPath); nil != err {
+		return fmt.Errorf("rename account: %s", err)
+	}
+	return nil
+}
+
+// Delete removes an account, the file is moved to the backup directory.
+func Delete(name string) error {
+	fmt.Println(":: Delete account:", name)
+
+	if err := checkName(name); nil != err {
+		return fmt.Errorf("delete account: %s", err)
+	}
+	path := accountPath(name)
+	if !doesAccountExist(path) {
+		return fmt.Errorf("delete account: not found: %s", path)
+	}
+	backup, err := backupAccount(name)
+	if err != nil {
+		return fmt.Errorf("delete account: backup: %s", err)
+	}
+	if err := os.Remove(path); nil != err {
+		return fmt.Errorf("delete account: %s", err)
+	}
+	fmt.Println(">> backup:", backup)
+	return nil
+}
+
+// ChangePassphrase re-encrypts an account with a new passphrase.
+func ChangePassphrase(name, pass, newPass string) error {
+	fmt.Println(":: Change passphrase:", name)
+
+	if err := checkName(name); nil != err {
+		return fmt.Errorf("change passphrase: %s", err)
+	}
+	acc, err := Load(name, pass)
+	if err != nil {
+		return fmt.Errorf("change passphrase: %w", err)
+	}
+	store, err := readKeyStore(accountPath(name))
+	if err != nil {
+		return fmt.Errorf("change passphrase: %s", err)
+	}
+	if _, err := backupAccount(name); nil != err {
+		return fmt.Errorf("change passphrase: backup: %s", err)
+	}
+	if err := saveConfigured(acc, newPass, accountPath(name), store.Devnet); nil != err {
+		return fmt.Errorf("change passphrase: %s", err)
+	}
+	return nil
+}
+
+// ExportMnemonic returns the 25 word mnemonic of an account, confirm
+// has to repeat the account name to make the export explicit.
+func ExportMnemonic(name, pass, confirm string) (string, error) {
+	if err := checkName(name); nil != err {
+		return "", fmt.Errorf("export mnemonic: %s", err)
+	}
+	if confirm != name {
+		return "", fmt.Errorf("export mnemonic: not confirmed, repeat the account name")
+	}
+	acc, err := Load(name, pass)
+	if err != nil {
+		return "", fmt.Errorf("export mnemonic: %w", err)
+	}
+	words, err := mnemonic.FromPrivateKey(acc.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("export mnemonic: %s", err)
+	}
+	return words, nil
+}
+
+// ImportMnemonic stores the account of a 25 word mnemonic.
+func ImportMnemonic(name, pass, words string) (crypto.Account, error) {
+	priv, err := mnemonic.ToPrivateKey(strings.Join(strings.Fields(words), " "))
+	if err != nil {
+		return crypto.Account{}, fmt.Errorf("import mnemonic: %s", err)
+	}
+	return importKey(name, pass, priv)
+}
+
+// ImportPrivateKey stores the account of a raw ed25519 private key,
+// either the 64 byte key or its 32 byte seed.
+func ImportPrivateKey(name, pass string, key []byte) (crypto.Account, error) {
+	switch len(key) {
+	case ed25519.SeedSize:
+		return importKey(name, pass, ed25519.NewKeyFromSeed(key))
+	case ed25519.PrivateKeySize:
+		// The public half has to match the seed, else signatures
+		// do not verify for the stored address
+		priv := ed25519.NewKeyFromSeed(key[:ed25519.SeedSize])
+		if !bytes.Equal(priv, key) {
+			return crypto.Account{}, fmt.Errorf("import key: public key does not match the seed")
+		}
+		return importKey(name, pass, priv)
+	}
+	return crypto.Account{}, fmt.Errorf("import key: invalid key size: %d", len(key))
+}
+
+func importKey(name, pass string, priv ed25519.PrivateKey) (crypto.Account, error) {
+	path := accountPath(name)
+	fmt.Println(":: Import account:", path)
+
+	if err := checkName(name); nil != err {
+		return crypto.Account{}, fmt.Errorf("import account: %s", err)
+	}
+	if doesAccountExist(path) {
+		return crypto.Account{}, fmt.Errorf("import account: file exists: %s", path)
+	}
+	acc, err := crypto.AccountFromPrivateKey(priv)
+	if err != nil {
+		return crypto.Account{}, fmt.Errorf("import account: %s", err)
+	}
+	if err := SaveAccountToFile(acc, pass, path); nil != err {
+		return crypto.Account{}, fmt.Errorf("import account: save file: %s", err)
+	}
+	return acc, nil
+}
+
+// backupAccount copies an account to <asset>/accounts/backup.
+func backupAccount(name string) (string, error) {
+	return backupFile(accountPath(name))
+}
+
+// backupFile copies a file to the backup directory next to it, the
+// time is added before the extension.
+func backupFile(src string) (string, error) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(filepath.Dir(src), "backup")
+	if err := os.MkdirAll(dir, 0700); nil != err {
+		return "", err
+	}
+	ext := filepath.Ext(src)
+	path := filepath.Join(dir, fmt.Sprintf(
+		"%s.%s%s", strings.TrimSuffix(filepath.Base(src), ext), time.Now().UTC().Format("20060102T150405.000000000"), ext,
+	))
+	return path, os.WriteFile(path, data, 0600)
+}
+
+func readKeyStore(path string) (KeyStore, error) {
+	store := KeyStore{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return store, err
+	}
+	err = json.Unmarshal(data, &store)
+	return store, err
+}
@@ -0,0 +1,30 @@
+package acc
+
+import (
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/crypto"
+)
+
+func TestImportPrivateKey(t *testing.T) {
+	setupAssets(t)
+	a := crypto.GenerateAccount()
+
+	imported, err := ImportPrivateKey("alice", "pass", a.PrivateKey)
+	if err != nil {
+		t.Fatalf("import: %s", err)
+	}
+	if imported.Address != a.Address {
+		t.Fatalf("address: expected %s, got %s", a.Address, imported.Address)
+	}
+
+	// A public half of another key
+	key := append([]byte{}, a.PrivateKey...)
+	copy(key[32:], crypto.GenerateAccount().PrivateKey[32:])
+	if _, err := ImportPrivateKey("bob", "pass", key); nil == err {
+		t.Fatalf("import of a mismatched key: expected an error")
+	}
+	if doesAccountExist(accountPath("bob")) {
+		t.Fatalf("mismatched key stored")
+	}
+}
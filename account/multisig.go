@@ -0,0 +1,278 @@
+package acc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+	net "github.com/vecno-io/go-pyteal/network"
+
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// ErrPartiallySigned is returned when a multisig transaction is below
+// its threshold and is saved for the other members to sign.
+var ErrPartiallySigned = errors.New("partially signed")
+
+// Multisig is a stored multisig account descriptor, it is saved as
+// <asset>/accounts/<name>.msig with its derived address.
+type Multisig struct {
+	Version   uint8            `json:"version"`
+	Threshold uint8            `json:"threshold"`
+	Members   []MultisigMember `json:"members"`
+	Addr      string           `json:"addr"`
+}
+
+// MultisigMember is a member address, Name refers to its keystore
+// when the member is stored locally.
+type MultisigMember struct {
+	Addr string `json:"addr"`
+	Name string `json:"name,omitempty"`
+}
+
+func multisigPath(name string) string {
+	return fmt.Sprintf("%s/%s.msig", accountsPath(), name)
+}
+
+// PartialPath is the path of a partially signed transaction file.
+func PartialPath(name string) string {
+	return fmt.Sprintf("%s/transactions/%s.ptx", cfg.AssetPath(), name)
+}
+
+// CreateMultisig stores a multisig descriptor, members given by name
+// only are resolved from their stored address.
+func CreateMultisig(name string, threshold uint8, members []MultisigMember) (Multisig, error) {
+	path := multisigPath(name)
+	fmt.Println(":: Create multisig:", path)
+
+	if err := checkName(name); nil != err {
+		return Multisig{}, fmt.Errorf("create multisig: %s", err)
+	}
+	if _, err := os.Stat(path); nil == err {
+		return Multisig{}, fmt.Errorf("create multisig: file exists: %s", path)
+	}
+
+	m := Multisig{Version: 1, Threshold: threshold}
+	for _, member := range members {
+		if len(member.Addr) == 0 {
+			stored, err := readKeyStore(accountPath(member.Name))
+			if err != nil {
+				return Multisig{}, fmt.Errorf("create multisig: member %s: %s", member.Name, err)
+			}
+			if len(stored.Addr) == 0 {
+				return Multisig{}, fmt.Errorf("create multisig: member %s: no stored address, upgrade it", member.Name)
+			}
+			member.Addr = stored.Addr
+		}
+		m.Members = append(m.Members, member)
+	}
+
+	addr, err := m.Address()
+	if err != nil {
+		return Multisig{}, fmt.Errorf("create multisig: %s", err)
+	}
+	m.Addr = addr.String()
+
+	out, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return Multisig{}, fmt.Errorf("create multisig: %s", err)
+	}
+	if err := writeFileAtomic(path, out); nil != err {
+		return Multisig{}, fmt.Errorf("create multisig: %s", err)
+	}
+	return m, nil
+}
+
+func LoadMultisig(name string) (Multisig, error) {
+	path := multisigPath(name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Multisig{}, fmt.Errorf("load multisig: %s", err)
+	}
+	m := Multisig{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Multisig{}, fmt.Errorf("load multisig: %s", err)
+	}
+	addr, err := m.Address()
+	if err != nil {
+		return Multisig{}, fmt.Errorf("load multisig: %s", err)
+	}
+	if addr.String() != m.Addr {
+		return Multisig{}, fmt.Errorf("load multisig: address mismatch: %s", m.Addr)
+	}
+	return m, nil
+}
+
+// Account returns the SDK multisig account of the descriptor.
+func (m Multisig) Account() (crypto.MultisigAccount, error) {
+	addrs := make([]types.Address, len(m.Members))
+	for i, member := range m.Members {
+		addr, err := types.DecodeAddress(member.Addr)
+		if err != nil {
+			return crypto.MultisigAccount{}, fmt.Errorf("member %d: %s", i, err)
+		}
+		addrs[i] = addr
+	}
+	return crypto.MultisigAccountWithParams(m.Version, m.Threshold, addrs)
+}
+
+// Address derives the multisig address from the members.
+func (m Multisig) Address() (types.Address, error) {
+	ma, err := m.Account()
+	if err != nil {
+		return types.Address{}, err
+	}
+	return ma.Address()
+}
+
+// NewPartial returns an unsigned multisig transaction to be signed by
+// the members with SignPartial.
+func (m Multisig) NewPartial(tx types.Transaction) ([]byte, error) {
+	ma, err := m.Account()
+	if err != nil {
+		return nil, err
+	}
+	addr, err := ma.Address()
+	if err != nil {
+		return nil, err
+	}
+
+	stx := types.SignedTxn{Txn: tx}
+	stx.Msig.Version = ma.Version
+	stx.Msig.Threshold = ma.Threshold
+	stx.Msig.Subsigs = make([]types.MultisigSubsig, len(ma.Pks))
+	for i, pk := range ma.Pks {
+		stx.Msig.Subsigs[i].Key = pk
+	}
+	if tx.Sender != addr {
+		stx.AuthAddr = addr
+	}
+	return msgpack.Encode(stx), nil
+}
+
+// SignPartial adds the signature of a member to a partial transaction.
+func (m Multisig) SignPartial(ptx []byte, member crypto.Account) ([]byte, error) {
+	ma, err := m.Account()
+	if err != nil {
+		return nil, err
+	}
+	_, out, err := crypto.AppendMultisigTransaction(member.PrivateKey, ma, ptx)
+	if err != nil {
+		return nil, fmt.Errorf("sign partial: %s", err)
+	}
+	return out, nil
+}
+
+// MergePartials combines the signatures of partial transactions that
+// were signed separately.
+func MergePartials(ptxs ...[]byte) ([]byte, error) {
+	if len(ptxs) == 1 {
+		return ptxs[0], nil
+	}
+	_, out, err := crypto.MergeMultisigTransactions(ptxs...)
+	if err != nil {
+		return nil, fmt.Errorf("merge partials: %s", err)
+	}
+	return out, nil
+}
+
+// PartialStatus returns the number of signatures and the threshold.
+func PartialStatus(ptx []byte) (int, int, error) {
+	stx := types.SignedTxn{}
+	if err := msgpack.Decode(ptx, &stx); err != nil {
+		return 0, 0, fmt.Errorf("decode partial: %s", err)
+	}
+	count := 0
+	for _, sub := range stx.Msig.Subsigs {
+		if sub.Sig != (types.Signature{}) {
+			count += 1
+		}
+	}
+	return count, int(stx.Msig.Threshold), nil
+}
+
+// SignMultisig signs a transaction with the members at hand, it returns
+// the partial transaction and ErrPartiallySigned below the threshold.
+func (m Multisig) SignMultisig(tx types.Transaction, members []crypto.Account) ([]byte, error) {
+	ptx, err := m.NewPartial(tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		if ptx, err = m.SignPartial(ptx, member); nil != err {
+			return nil, err
+		}
+	}
+	count, threshold, err := PartialStatus(ptx)
+	if err != nil {
+		return nil, err
+	}
+	if count < threshold {
+		return ptx, fmt.Errorf("%d of %d signatures: %w", count, threshold, ErrPartiallySigned)
+	}
+	return ptx, nil
+}
+
+func SavePartial(name string, ptx []byte) error {
+	path := PartialPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); nil != err {
+		return fmt.Errorf("save partial: %s", err)
+	}
+	if err := writeFileAtomic(path, ptx); nil != err {
+		return fmt.Errorf("save partial: %s", err)
+	}
+	return nil
+}
+
+func LoadPartial(name string) ([]byte, error) {
+	ptx, err := os.ReadFile(PartialPath(name))
+	if err != nil {
+		return nil, fmt.Errorf("load partial: %s", err)
+	}
+	return ptx, nil
+}
+
+// SignPartialFile signs a saved partial transaction in place with a
+// stored member account of the multisig.
+func SignPartialFile(name, msigName, member, pass string) error {
+	fmt.Println(":: Sign partial:", PartialPath(name), "as", member)
+
+	m, err := LoadMultisig(msigName)
+	if err != nil {
+		return err
+	}
+	acc, err := Load(member, pass)
+	if err != nil {
+		return err
+	}
+	ptx, err := LoadPartial(name)
+	if err != nil {
+		return err
+	}
+	if ptx, err = m.SignPartial(ptx, acc); nil != err {
+		return err
+	}
+	count, threshold, err := PartialStatus(ptx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(">> %d of %d signatures\n", count, threshold)
+	return SavePartial(name, ptx)
+}
+
+// SubmitPartial sends a fully signed partial transaction.
+func SubmitPartial(ptx []byte, opts net.SendOptions) (net.SendResult, error) {
+	count, threshold, err := PartialStatus(ptx)
+	if err != nil {
+		return net.SendResult{}, err
+	}
+	if count < threshold {
+		return net.SendResult{}, fmt.Errorf("submit partial: %d of %d signatures: %w", count, threshold, ErrPartiallySigned)
+	}
+	return net.Send(ptx, opts)
+}
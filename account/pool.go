@@ -0,0 +1,289 @@
+package acc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+	net "github.com/vecno-io/go-pyteal/network"
+
+	"github.com/algorand/go-algorand-sdk/client/kmd"
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/future"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// DevWallet is the kmd wallet holding the genesis accounts of a
+// private network, it has no password.
+const DevWallet = "unencrypted-default-wallet"
+
+// maxGroupSize is the maximum number of transactions in a group.
+const maxGroupSize = 16
+
+// TB is the part of testing.TB used by the pool.
+type TB interface {
+	Helper()
+	Cleanup(func())
+	Logf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// Pool is a set of funded throwaway devnet accounts, Release sweeps
+// their balances back to the dispenser.
+type Pool struct {
+	Accounts []crypto.Account
+
+	dispenser Signer
+	params    net.ParamsOptions
+	released  bool
+}
+
+// swept holds accounts that were closed out, they are borrowed by new
+// pools before new accounts are generated.
+var swept = struct {
+	sync.Mutex
+	accounts []crypto.Account
+}{}
+
+// DevDispenser returns a signer for the genesis account with the
+// largest balance in the devnet wallet.
+func DevDispenser() (s KmdSigner, err error) {
+	if cfg.Target() != cfg.Devnet {
+		return KmdSigner{}, fmt.Errorf("dispenser: %w", ErrDevnetOnly)
+	}
+	err = net.WithLock(net.LockShared, func() error {
+		s, err = devDispenser()
+		return err
+	})
+	return s, err
+}
+
+func devDispenser() (KmdSigner, error) {
+	cl, err := net.MakeClient()
+	if err != nil {
+		return KmdSigner{}, fmt.Errorf("dispenser: make client: %s", err)
+	}
+
+	best, balance := "", uint64(0)
+	err = withKmdWallet(DevWallet, "", func(kcl kmd.Client, handle string) error {
+		keys, err := kcl.ListKeys(handle)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys.Addresses {
+			info, err := cl.AccountInformation(key).Do(context.Background())
+			if err != nil {
+				return err
+			}
+			if info.Amount > balance {
+				best, balance = key, info.Amount
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return KmdSigner{}, fmt.Errorf("dispenser: %s", err)
+	}
+	if len(best) == 0 {
+		return KmdSigner{}, fmt.Errorf("dispenser: no funded account in %s", DevWallet)
+	}
+	return NewKmdSigner(DevWallet, "", best)
+}
+
+// NewPool funds n accounts with amount each in grouped payments from
+// the devnet dispenser.
+func NewPool(n int, amount uint64) (*Pool, error) {
+	return NewPoolWithParams(n, amount, net.ParamsOptions{})
+}
+
+// NewPoolWithParams is NewPool with params options, they are used
+// for the funding and the release.
+func NewPoolWithParams(n int, amount uint64, opts net.ParamsOptions) (*Pool, error) {
+	fmt.Println(":: Create account pool:", n)
+
+	dispenser, err := DevDispenser()
+	if err != nil {
+		return nil, fmt.Errorf("account pool: %w", err)
+	}
+	p := &Pool{dispenser: dispenser, params: opts}
+
+	swept.Lock()
+	for len(p.Accounts) < n && len(swept.accounts) > 0 {
+		last := len(swept.accounts) - 1
+		p.Accounts = append(p.Accounts, swept.accounts[last])
+		swept.accounts = swept.accounts[:last]
+	}
+	swept.Unlock()
+	for len(p.Accounts) < n {
+		p.Accounts = append(p.Accounts, crypto.GenerateAccount())
+	}
+
+	targets := make([]Funding, len(p.Accounts))
+	for i, a := range p.Accounts {
+		targets[i] = Funding{a.Address.String(), amount}
+	}
+	for start := 0; start < len(targets); start += maxGroupSize {
+		end := start + maxGroupSize
+		if end > len(targets) {
+			end = len(targets)
+		}
+		if _, err := DevFundingMany(targets[start:end], opts); nil != err {
+			p.Release()
+			return nil, fmt.Errorf("account pool: funded %d of %d accounts: %w", start, len(targets), err)
+		}
+	}
+	return p, nil
+}
+
+// NewTestPool creates a pool that is released when the test ends.
+func NewTestPool(t TB, n int, amount uint64) *Pool {
+	t.Helper()
+	p, err := NewPool(n, amount)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	t.Cleanup(func() {
+		if err := p.Release(); nil != err {
+			t.Logf("%s", err)
+		}
+	})
+	return p
+}
+
+// Signer returns a signer for the account at index i.
+func (p *Pool) Signer(i int) Signer {
+	return NewAccountSigner(p.Accounts[i])
+}
+
+// Release opts the accounts out of their apps and assets and closes
+// them out to the dispenser. Accounts that created apps or assets are
+// left as is and reported in the error.
+func (p *Pool) Release() error {
+	if p.released {
+		return nil
+	}
+	p.released = true
+	fmt.Println(":: Release account pool:", len(p.Accounts))
+
+	cl, err := net.MakeClient()
+	if err != nil {
+		return fmt.Errorf("release pool: make client: %s", err)
+	}
+	failed := 0
+	var last error
+	for _, a := range p.Accounts {
+		if err := p.sweep(cl, a); nil != err {
+			failed += 1
+			last = fmt.Errorf("%s: %w", a.Address, err)
+			continue
+		}
+		swept.Lock()
+		swept.accounts = append(swept.accounts, a)
+		swept.Unlock()
+	}
+	if failed > 0 {
+		return fmt.Errorf("release pool: %d of %d accounts failed, last: %w", failed, len(p.Accounts), last)
+	}
+	return nil
+}
+
+func (p *Pool) sweep(cl *algod.Client, a crypto.Account) error {
+	addr := a.Address.String()
+	info, err := cl.AccountInformation(addr).Do(context.Background())
+	if err != nil {
+		return err
+	}
+	if info.Amount == 0 {
+		return nil
+	}
+	if len(info.CreatedApps) > 0 || len(info.CreatedAssets) > 0 {
+		return fmt.Errorf("holds created apps or assets")
+	}
+
+	params, err := net.MakeTxnParamsWith(p.params)
+	if err != nil {
+		return err
+	}
+	txs := []types.Transaction{}
+	for _, app := range info.AppsLocalState {
+		tx, err := future.MakeApplicationClearStateTx(
+			app.Id, nil, nil, nil, nil, params, a.Address, nil, types.Digest{}, [32]byte{}, types.ZeroAddress,
+		)
+		if err != nil {
+			return err
+		}
+		txs = append(txs, tx)
+	}
+	for _, asset := range info.Assets {
+		if asset.IsFrozen {
+			return fmt.Errorf("asset %d is frozen", asset.AssetId)
+		}
+		to, err := p.closeAssetTo(cl, asset.AssetId)
+		if err != nil {
+			return err
+		}
+		tx, err := future.MakeAssetTransferTxn(addr, to, 0, nil, params, to, asset.AssetId)
+		if err != nil {
+			return err
+		}
+		txs = append(txs, tx)
+	}
+	tx, err := future.MakePaymentTxn(addr, p.dispenser.Address().String(), 0, nil, p.dispenser.Address().String(), params)
+	if err != nil {
+		return err
+	}
+	txs = append(txs, tx)
+	return sendGroups(NewAccountSigner(a), txs)
+}
+
+// closeAssetTo returns the creator of the asset, it holds the asset for
+// as long as it exists. Holdings of destroyed assets close to the
+// dispenser, the node only removes them.
+func (p *Pool) closeAssetTo(cl *algod.Client, id uint64) (string, error) {
+	asset, err := cl.GetAssetByID(id).Do(context.Background())
+	if err != nil {
+		if strings.Contains(err.Error(), "404") {
+			return p.dispenser.Address().String(), nil
+		}
+		return "", fmt.Errorf("asset %d: %s", id, err)
+	}
+	if len(asset.Params.Creator) == 0 {
+		return p.dispenser.Address().String(), nil
+	}
+	return asset.Params.Creator, nil
+}
+
+// sendGroups signs and sends the transactions of one account in groups
+// of the maximum size, each group is confirmed before the next is sent.
+func sendGroups(s Signer, txs []types.Transaction) error {
+	for start := 0; start < len(txs); start += maxGroupSize {
+		end := start + maxGroupSize
+		if end > len(txs) {
+			end = len(txs)
+		}
+		group := txs[start:end]
+		gid, err := crypto.ComputeGroupID(group)
+		if err != nil {
+			return fmt.Errorf("group: %s", err)
+		}
+		for i := range group {
+			group[i].Group = gid
+		}
+
+		signed, err := s.SignGroup(group)
+		if err != nil {
+			return fmt.Errorf("sign: %w", err)
+		}
+		raw := []byte{}
+		for _, stx := range signed {
+			raw = append(raw, stx...)
+		}
+		if _, err := net.Send(raw, net.DefaultSendOptions()); nil != err {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package acc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	net "github.com/vecno-io/go-pyteal/network"
+
+	"github.com/algorand/go-algorand-sdk/future"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// ErrSignerUnavailable is returned when the auth address of an
+// account is not stored locally.
+var ErrSignerUnavailable = errors.New("signer not available")
+
+// PassFunc returns the passphrase of a stored account by name.
+type PassFunc func(name string) (string, error)
+
+// SamePass uses one passphrase for all accounts.
+func SamePass(pass string) PassFunc {
+	return func(string) (string, error) {
+		return pass, nil
+	}
+}
+
+// AuthAddress returns the address that signs for an account, this is
+// the account itself unless it is rekeyed.
+func AuthAddress(address string) (string, error) {
+	cl, err := net.MakeClient()
+	if err != nil {
+		return "", fmt.Errorf("auth address: make client: %s", err)
+	}
+	info, err := cl.AccountInformation(address).Do(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("auth address: get: %s", err)
+	}
+	if len(info.AuthAddr) > 0 {
+		return info.AuthAddr, nil
+	}
+	return address, nil
+}
+
+// ResolveSigner looks up the auth address of an account and returns a
+// signer with the stored account or multisig members for it. Multisig
+// members that are not stored or have no passphrase are skipped.
+func ResolveSigner(address string, pass PassFunc) (Signer, error) {
+	auth, err := AuthAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored, err := Find(auth); nil == err {
+		s, err := NewKeystoreSigner(stored.Name, pass)
+		if err != nil {
+			return nil, fmt.Errorf("resolve signer: %w", err)
+		}
+		return s, nil
+	}
+
+	m, err := findMultisig(auth)
+	if err != nil {
+		return nil, fmt.Errorf("resolve signer: auth address %s of %s: %w", auth, address, ErrSignerUnavailable)
+	}
+	s := NewMultisigSigner(m)
+	for _, member := range m.Members {
+		if len(member.Name) == 0 || !doesAccountExist(accountPath(member.Name)) {
+			continue
+		}
+		p, err := pass(member.Name)
+		if err != nil {
+			continue
+		}
+		a, err := Load(member.Name, p)
+		if err != nil {
+			return nil, fmt.Errorf("resolve signer: %w", err)
+		}
+		s.Members = append(s.Members, a)
+	}
+	if len(s.Members) == 0 {
+		return nil, fmt.Errorf("resolve signer: multisig %s has no local members: %w", auth, ErrSignerUnavailable)
+	}
+	return s, nil
+}
+
+// Rekey sets the auth address of a stored account or multisig to
+// another stored account or multisig, signed by its current auth address.
+func Rekey(name, to string, pass PassFunc) (net.SendResult, error) {
+	return RekeyWithParams(name, to, pass, net.ParamsOptions{})
+}
+
+func RekeyWithParams(name, to string, pass PassFunc, opts net.ParamsOptions) (net.SendResult, error) {
+	fmt.Println(":: Rekey account:", name, "to", to)
+
+	addr, err := addressOf(name)
+	if err != nil {
+		return net.SendResult{}, fmt.Errorf("rekey: %s", err)
+	}
+	target, err := addressOf(to)
+	if err != nil {
+		return net.SendResult{}, fmt.Errorf("rekey: %s", err)
+	}
+	return rekey(name, addr, target, pass, opts)
+}
+
+// RekeyBack resets the auth address of an account to itself.
+func RekeyBack(name string, pass PassFunc) (net.SendResult, error) {
+	return RekeyBackWithParams(name, pass, net.ParamsOptions{})
+}
+
+func RekeyBackWithParams(name string, pass PassFunc, opts net.ParamsOptions) (net.SendResult, error) {
+	fmt.Println(":: Rekey account back:", name)
+
+	addr, err := addressOf(name)
+	if err != nil {
+		return net.SendResult{}, fmt.Errorf("rekey: %s", err)
+	}
+	return rekey(name, addr, addr, pass, opts)
+}
+
+func rekey(name, addr, target string, pass PassFunc, opts net.ParamsOptions) (net.SendResult, error) {
+	params, err := net.MakeTxnParamsWith(opts)
+	if err != nil {
+		return net.SendResult{}, fmt.Errorf("rekey: params: %s", err)
+	}
+	tx, err := future.MakePaymentTxn(addr, addr, 0, nil, "", params)
+	if err != nil {
+		return net.SendResult{}, fmt.Errorf("rekey: make tx: %s", err)
+	}
+	tx = net.ApplyMinFee(tx, params)
+	if tx.RekeyTo, err = types.DecodeAddress(target); nil != err {
+		return net.SendResult{}, fmt.Errorf("rekey: %s", err)
+	}
+
+	signer, err := ResolveSigner(addr, pass)
+	if err != nil {
+		return net.SendResult{}, fmt.Errorf("rekey: %w", err)
+	}
+	stx, err := signer.SignTransaction(tx)
+	if errors.Is(err, ErrPartiallySigned) {
+		partial := fmt.Sprintf("rekey-%s", name)
+		if err := SavePartial(partial, stx); nil != err {
+			return net.SendResult{}, fmt.Errorf("rekey: %s", err)
+		}
+		fmt.Println(">> Partial rekey tx saved:", PartialPath(partial))
+		return net.SendResult{}, fmt.Errorf("rekey: %w", err)
+	}
+	if err != nil {
+		return net.SendResult{}, fmt.Errorf("rekey: sign: %s", err)
+	}
+
+	res, err := net.Send(stx, net.DefaultSendOptions())
+	if err != nil {
+		return res, fmt.Errorf("rekey: %w", err)
+	}
+	fmt.Println(">> Rekeyed", addr, "to", target)
+	return res, nil
+}
+
+// addressOf returns the address of a stored account or multisig.
+func addressOf(name string) (string, error) {
+	if doesAccountExist(accountPath(name)) {
+		store, err := readKeyStore(accountPath(name))
+		if err != nil {
+			return "", err
+		}
+		if len(store.Addr) == 0 {
+			return "", fmt.Errorf("account %s has no stored address, upgrade it", name)
+		}
+		return store.Addr, nil
+	}
+	if _, err := os.Stat(multisigPath(name)); nil == err {
+		m, err := LoadMultisig(name)
+		if err != nil {
+			return "", err
+		}
+		return m.Addr, nil
+	}
+	return "", fmt.Errorf("account not found: %s", name)
+}
+
+func findMultisig(address string) (Multisig, error) {
+	files, err := filepath.Glob(filepath.Join(accountsPath(), "*.msig"))
+	if err != nil {
+		return Multisig{}, err
+	}
+	for _, file := range files {
+		m, err := LoadMultisig(strings.TrimSuffix(filepath.Base(file), ".msig"))
+		if nil == err && m.Addr == address {
+			return m, nil
+		}
+	}
+	return Multisig{}, fmt.Errorf("multisig not found: %s", address)
+}
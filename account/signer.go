@@ -0,0 +1,324 @@
+package acc
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	net "github.com/vecno-io/go-pyteal/network"
+
+	"github.com/algorand/go-algorand-sdk/client/kmd"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// Signer signs transactions without exposing its keys. Address is the
+// address whose keys sign, for a rekeyed sender its auth address.
+type Signer interface {
+	Address() types.Address
+	SignTransaction(tx types.Transaction) ([]byte, error)
+	SignGroup(txs []types.Transaction) ([][]byte, error)
+}
+
+// AccountSigner signs with a key held in memory.
+type AccountSigner struct {
+	Account crypto.Account
+}
+
+func NewAccountSigner(a crypto.Account) AccountSigner {
+	return AccountSigner{Account: a}
+}
+
+func (s AccountSigner) Address() types.Address {
+	return s.Account.Address
+}
+
+func (s AccountSigner) SignTransaction(tx types.Transaction) ([]byte, error) {
+	_, stx, err := crypto.SignTransaction(s.Account.PrivateKey, tx)
+	return stx, err
+}
+
+func (s AccountSigner) SignGroup(txs []types.Transaction) ([][]byte, error) {
+	return signGroup(s.SignTransaction, txs)
+}
+
+// KeystoreSigner signs with a stored account, the keystore is decrypted
+// for each call and the key is not kept.
+type KeystoreSigner struct {
+	name string
+	pass PassFunc
+	addr types.Address
+}
+
+// NewKeystoreSigner reads the address of a stored account, files
+// without a stored address are decrypted once to recover it.
+func NewKeystoreSigner(name string, pass PassFunc) (KeystoreSigner, error) {
+	s := KeystoreSigner{name: name, pass: pass}
+	store, err := readKeyStore(accountPath(name))
+	if err != nil {
+		return s, fmt.Errorf("keystore signer: %s", err)
+	}
+	if len(store.Addr) == 0 {
+		a, err := s.load()
+		if err != nil {
+			return s, fmt.Errorf("keystore signer: %w", err)
+		}
+		s.addr = a.Address
+		return s, nil
+	}
+	if s.addr, err = types.DecodeAddress(store.Addr); nil != err {
+		return s, fmt.Errorf("keystore signer: %s", err)
+	}
+	return s, nil
+}
+
+func (s KeystoreSigner) load() (crypto.Account, error) {
+	p, err := s.pass(s.name)
+	if err != nil {
+		return crypto.Account{}, err
+	}
+	return Load(s.name, p)
+}
+
+func (s KeystoreSigner) Address() types.Address {
+	return s.addr
+}
+
+func (s KeystoreSigner) SignTransaction(tx types.Transaction) ([]byte, error) {
+	a, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return AccountSigner{a}.SignTransaction(tx)
+}
+
+func (s KeystoreSigner) SignGroup(txs []types.Transaction) ([][]byte, error) {
+	a, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return AccountSigner{a}.SignGroup(txs)
+}
+
+// KmdSigner signs with a key of a kmd wallet on the primary node.
+type KmdSigner struct {
+	Wallet string
+	Pass   string
+	addr   types.Address
+}
+
+// NewKmdSigner checks the wallet holds the key of the address.
+func NewKmdSigner(wallet, pass, address string) (KmdSigner, error) {
+	s := KmdSigner{Wallet: wallet, Pass: pass}
+	addr, err := types.DecodeAddress(address)
+	if err != nil {
+		return s, fmt.Errorf("kmd signer: %s", err)
+	}
+	s.addr = addr
+
+	err = withKmdWallet(wallet, pass, func(cl kmd.Client, handle string) error {
+		keys, err := cl.ListKeys(handle)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys.Addresses {
+			if key == address {
+				return nil
+			}
+		}
+		return fmt.Errorf("no key for %s in wallet %s", address, wallet)
+	})
+	if err != nil {
+		return s, fmt.Errorf("kmd signer: %s", err)
+	}
+	return s, nil
+}
+
+func (s KmdSigner) Address() types.Address {
+	return s.addr
+}
+
+func (s KmdSigner) SignTransaction(tx types.Transaction) ([]byte, error) {
+	out, err := s.SignGroup([]types.Transaction{tx})
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+func (s KmdSigner) SignGroup(txs []types.Transaction) ([][]byte, error) {
+	out := make([][]byte, len(txs))
+	err := withKmdWallet(s.Wallet, s.Pass, func(cl kmd.Client, handle string) error {
+		for i, tx := range txs {
+			var res kmd.SignTransactionResponse
+			var err error
+			if tx.Sender == s.addr {
+				res, err = cl.SignTransaction(handle, s.Pass, tx)
+			} else {
+				res, err = cl.SignTransactionWithSpecificPublicKey(handle, s.Pass, tx, ed25519.PublicKey(s.addr[:]))
+			}
+			if err != nil {
+				return fmt.Errorf("tx %d: %s", i, err)
+			}
+			out[i] = res.SignedTransaction
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kmd sign: %s", err)
+	}
+	return out, nil
+}
+
+// withKmdWallet opens a wallet handle by wallet name for the call.
+func withKmdWallet(wallet, pass string, fn func(cl kmd.Client, handle string) error) error {
+	cl, err := net.MakeKmdClient()
+	if err != nil {
+		return err
+	}
+	list, err := cl.ListWallets()
+	if err != nil {
+		return fmt.Errorf("list wallets: %s", err)
+	}
+	id := ""
+	for _, w := range list.Wallets {
+		if w.Name == wallet {
+			id = w.ID
+			break
+		}
+	}
+	if len(id) == 0 {
+		return fmt.Errorf("wallet not found: %s", wallet)
+	}
+	handle, err := cl.InitWalletHandle(id, pass)
+	if err != nil {
+		return fmt.Errorf("open wallet: %s", err)
+	}
+	defer cl.ReleaseWalletHandle(handle.WalletHandleToken)
+	return fn(cl, handle.WalletHandleToken)
+}
+
+// LogicSigner signs with a logic signature, either a contract account
+// or a delegated signature.
+type LogicSigner struct {
+	Account crypto.LogicSigAccount
+	addr    types.Address
+}
+
+func NewLogicSigner(lsa crypto.LogicSigAccount) (LogicSigner, error) {
+	addr, err := lsa.Address()
+	if err != nil {
+		return LogicSigner{}, fmt.Errorf("logic signer: %s", err)
+	}
+	return LogicSigner{Account: lsa, addr: addr}, nil
+}
+
+func (s LogicSigner) Address() types.Address {
+	return s.addr
+}
+
+func (s LogicSigner) SignTransaction(tx types.Transaction) ([]byte, error) {
+	_, stx, err := crypto.SignLogicSigAccountTransaction(s.Account, tx)
+	return stx, err
+}
+
+func (s LogicSigner) SignGroup(txs []types.Transaction) ([][]byte, error) {
+	return signGroup(s.SignTransaction, txs)
+}
+
+// MultisigSigner signs with the local members of a multisig, below the
+// threshold the partial transactions are returned with ErrPartiallySigned.
+type MultisigSigner struct {
+	Multisig Multisig
+	Members  []crypto.Account
+}
+
+func NewMultisigSigner(m Multisig, members ...crypto.Account) MultisigSigner {
+	return MultisigSigner{Multisig: m, Members: members}
+}
+
+func (s MultisigSigner) Address() types.Address {
+	addr, _ := types.DecodeAddress(s.Multisig.Addr)
+	return addr
+}
+
+func (s MultisigSigner) SignTransaction(tx types.Transaction) ([]byte, error) {
+	return s.Multisig.SignMultisig(tx, s.Members)
+}
+
+func (s MultisigSigner) SignGroup(txs []types.Transaction) ([][]byte, error) {
+	out := make([][]byte, len(txs))
+	var partial error
+	for i, tx := range txs {
+		stx, err := s.SignTransaction(tx)
+		if errors.Is(err, ErrPartiallySigned) {
+			partial = err
+		} else if err != nil {
+			return nil, fmt.Errorf("tx %d: %s", i, err)
+		}
+		out[i] = stx
+	}
+	return out, partial
+}
+
+// ExternalSigner runs a command for each transaction, the msgpack
+// encoded transaction is written to its stdin and the msgpack encoded
+// signed transaction is read from its stdout.
+type ExternalSigner struct {
+	Command string
+	Args    []string
+	addr    types.Address
+}
+
+func NewExternalSigner(address, command string, args ...string) (ExternalSigner, error) {
+	addr, err := types.DecodeAddress(address)
+	if err != nil {
+		return ExternalSigner{}, fmt.Errorf("external signer: %s", err)
+	}
+	return ExternalSigner{Command: command, Args: args, addr: addr}, nil
+}
+
+func (s ExternalSigner) Address() types.Address {
+	return s.addr
+}
+
+func (s ExternalSigner) SignTransaction(tx types.Transaction) ([]byte, error) {
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	cmd := exec.Command(s.Command, s.Args...)
+	cmd.Stdin = bytes.NewReader(msgpack.Encode(tx))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); nil != err {
+		return nil, fmt.Errorf("external sign: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	// The signer is not trusted to sign what it was given
+	stx := types.SignedTxn{}
+	if err := msgpack.Decode(stdout.Bytes(), &stx); nil != err {
+		return nil, fmt.Errorf("external sign: decode: %s", err)
+	}
+	if crypto.TransactionIDString(stx.Txn) != crypto.TransactionIDString(tx) {
+		return nil, fmt.Errorf("external sign: signed a different transaction")
+	}
+	return stdout.Bytes(), nil
+}
+
+func (s ExternalSigner) SignGroup(txs []types.Transaction) ([][]byte, error) {
+	return signGroup(s.SignTransaction, txs)
+}
+
+func signGroup(sign func(types.Transaction) ([]byte, error), txs []types.Transaction) ([][]byte, error) {
+	out := make([][]byte, len(txs))
+	for i, tx := range txs {
+		stx, err := sign(tx)
+		if err != nil {
+			return nil, fmt.Errorf("tx %d: %w", i, err)
+		}
+		out[i] = stx
+	}
+	return out, nil
+}
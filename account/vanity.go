@@ -0,0 +1,206 @@
+package acc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/crypto"
+)
+
+// addressAlphabet are the base32 characters of an address.
+const addressAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+
+// addressLastChars are the values of the last address character, it
+// holds the last 3 bits of the checksum.
+const addressLastChars = "AEIMQUY4"
+
+// VanityPattern is the prefix and suffix a vanity address must have.
+type VanityPattern struct {
+	Prefix string
+	Suffix string
+}
+
+// VanityProgress is reported while searching, the search is memoryless
+// so the estimate does not shrink with the attempts made.
+type VanityProgress struct {
+	Attempts   uint64
+	Difficulty float64
+	Rate       float64
+	Elapsed    time.Duration
+	Estimate   time.Duration
+}
+
+// VanityOptions sets the search, zero workers uses all cpu cores.
+type VanityOptions struct {
+	Workers  int
+	Interval time.Duration
+	Progress func(VanityProgress)
+}
+
+func DefaultVanityOptions() VanityOptions {
+	return VanityOptions{
+		Interval: 2 * time.Second,
+		Progress: func(p VanityProgress) {
+			fmt.Printf(">> %d attempts, %.0f/s, expected %s\n", p.Attempts, p.Rate, p.Estimate.Round(time.Second))
+		},
+	}
+}
+
+// Validate checks the pattern can match an address.
+func (p VanityPattern) Validate() error {
+	if len(p.Prefix) == 0 && len(p.Suffix) == 0 {
+		return fmt.Errorf("empty vanity pattern")
+	}
+	if len(p.Prefix)+len(p.Suffix) > 58 {
+		return fmt.Errorf("vanity pattern too long")
+	}
+	for _, c := range p.Prefix + p.Suffix {
+		if !strings.ContainsRune(addressAlphabet, c) {
+			return fmt.Errorf("invalid vanity character: %q", c)
+		}
+	}
+	if n := len(p.Suffix); n > 0 && !strings.ContainsRune(addressLastChars, rune(p.Suffix[n-1])) {
+		return fmt.Errorf("invalid last address character: %q, one of %s", p.Suffix[n-1], addressLastChars)
+	}
+	return nil
+}
+
+// Difficulty is the expected number of attempts to find a match.
+func (p VanityPattern) Difficulty() float64 {
+	d := math.Pow(32, float64(len(p.Prefix)))
+	if n := len(p.Suffix); n > 0 {
+		d *= math.Pow(32, float64(n-1)) * float64(len(addressLastChars))
+	}
+	return d
+}
+
+func (p VanityPattern) Match(address string) bool {
+	return strings.HasPrefix(address, p.Prefix) && strings.HasSuffix(address, p.Suffix)
+}
+
+// CreateVanity searches an account matching the pattern on all cores
+// and stores it like Create.
+func CreateVanity(ctx context.Context, name, pass string, pattern VanityPattern) (crypto.Account, error) {
+	return CreateVanityWith(ctx, name, pass, pattern, DefaultVanityOptions())
+}
+
+func CreateVanityWith(ctx context.Context, name, pass string, pattern VanityPattern, opts VanityOptions) (crypto.Account, error) {
+	path := accountPath(name)
+	fmt.Println(":: Create vanity account:", path)
+
+	if err := checkName(name); nil != err {
+		return crypto.Account{}, fmt.Errorf("create account: %s", err)
+	}
+	if doesAccountExist(path) {
+		return crypto.Account{}, fmt.Errorf("create account: file exists: %s", path)
+	}
+	if err := pattern.Validate(); nil != err {
+		return crypto.Account{}, fmt.Errorf("create account: %s", err)
+	}
+
+	acc, err := SearchVanity(ctx, pattern, opts)
+	if err != nil {
+		return crypto.Account{}, fmt.Errorf("create account: %w", err)
+	}
+	if err := SaveAccountToFile(acc, pass, path); nil != err {
+		return crypto.Account{}, fmt.Errorf("create account: save file : %s", err)
+	}
+	return acc, nil
+}
+
+// SearchVanity searches an account matching the pattern until found
+// or the context is done.
+func SearchVanity(ctx context.Context, pattern VanityPattern, opts VanityOptions) (crypto.Account, error) {
+	if err := pattern.Validate(); nil != err {
+		return crypto.Account{}, err
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	fmt.Printf(">> vanity search: %s...%s, %.0f expected attempts, %d workers\n",
+		pattern.Prefix, pattern.Suffix, pattern.Difficulty(), workers)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	attempts := uint64(0)
+	found := make(chan crypto.Account, 1)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			count := uint64(0)
+			for {
+				a := crypto.GenerateAccount()
+				if count += 1; count == 256 {
+					atomic.AddUint64(&attempts, count)
+					count = 0
+					if nil != ctx.Err() {
+						return
+					}
+				}
+				if pattern.Match(a.Address.String()) {
+					atomic.AddUint64(&attempts, count)
+					select {
+					case found <- a:
+					default:
+					}
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+	start := time.Now()
+	progress := func() VanityProgress {
+		p := VanityProgress{
+			Attempts:   atomic.LoadUint64(&attempts),
+			Difficulty: pattern.Difficulty(),
+			Elapsed:    time.Since(start),
+		}
+		if p.Elapsed > 0 {
+			p.Rate = float64(p.Attempts) / p.Elapsed.Seconds()
+		}
+		if p.Rate > 0 {
+			p.Estimate = time.Duration(p.Difficulty / p.Rate * float64(time.Second))
+		}
+		return p
+	}
+
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case a := <-found:
+			wg.Wait()
+			p := progress()
+			fmt.Printf(">> found %s after %d attempts in %s\n", a.Address, p.Attempts, p.Elapsed.Round(time.Millisecond))
+			return a, nil
+		case <-ctx.Done():
+			wg.Wait()
+			select {
+			case a := <-found:
+				return a, nil
+			default:
+			}
+			return crypto.Account{}, fmt.Errorf("vanity search: %w", ctx.Err())
+		case <-ticker.C:
+			if nil != opts.Progress {
+				opts.Progress(progress())
+			}
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package acc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	net "github.com/vecno-io/go-pyteal/network"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// ErrInsufficientBalance is returned when the spendable amount of an
+// account does not cover a payment and a higher minimum balance.
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// Minimum balance values of the consensus protocol in microalgos.
+const (
+	BaseMinBalance        = 100000
+	AssetMinBalance       = 100000
+	AppCreateMinBalance   = 100000
+	AppPageMinBalance     = 100000
+	AppOptInMinBalance    = 100000
+	SchemaEntryMinBalance = 25000
+	SchemaUintMinBalance  = 3500
+	SchemaBytesMinBalance = 25000
+)
+
+// MinBalance is the minimum balance of an account by cause, Schema
+// covers the global state of created apps and the local states.
+type MinBalance struct {
+	Base       uint64
+	Assets     uint64
+	Apps       uint64
+	ExtraPages uint64
+	OptIns     uint64
+	Schema     uint64
+}
+
+func (m MinBalance) Total() uint64 {
+	return m.Base + m.Assets + m.Apps + m.ExtraPages + m.OptIns + m.Schema
+}
+
+// AppView is an app created by the account.
+type AppView struct {
+	Id         uint64
+	Global     types.StateSchema
+	Local      types.StateSchema
+	ExtraPages uint32
+}
+
+// LocalStateView is an app the account opted in to.
+type LocalStateView struct {
+	AppId   uint64
+	Schema  types.StateSchema
+	Used    int
+	OptedIn uint64
+}
+
+// AssetView is an asset held by the account.
+type AssetView struct {
+	Id      uint64
+	Amount  uint64
+	Creator string
+	Frozen  bool
+}
+
+// AccountView is the state of an account with the values derived from
+// it, Balance includes the pending rewards.
+type AccountView struct {
+	Address  string
+	AuthAddr string
+	Round    uint64
+
+	Balance        uint64
+	PendingRewards uint64
+	MinBalance     MinBalance
+	Spendable      uint64
+
+	CreatedApps   []AppView
+	CreatedAssets []uint64
+	LocalStates   []LocalStateView
+	Assets        []AssetView
+}
+
+// View returns the account view of an address.
+func View(address string) (AccountView, error) {
+	cl, err := net.MakeClient()
+	if err != nil {
+		return AccountView{}, fmt.Errorf("account view: make client: %s", err)
+	}
+	info, err := cl.AccountInformation(address).Do(context.Background())
+	if err != nil {
+		return AccountView{}, fmt.Errorf("account view: get: %s", err)
+	}
+	return ViewFromInfo(info), nil
+}
+
+// ViewStored returns the account view of a stored account or multisig,
+// the keystore is not decrypted.
+func ViewStored(name string) (AccountView, error) {
+	addr, err := addressOf(name)
+	if err != nil {
+		return AccountView{}, fmt.Errorf("account view: %s", err)
+	}
+	return View(addr)
+}
+
+func ViewFromInfo(info models.Account) AccountView {
+	v := AccountView{
+		Address:        info.Address,
+		AuthAddr:       info.AuthAddr,
+		Round:          info.Round,
+		Balance:        info.Amount,
+		PendingRewards: info.PendingRewards,
+	}
+
+	for _, app := range info.CreatedApps {
+		if app.Deleted {
+			continue
+		}
+		v.CreatedApps = append(v.CreatedApps, AppView{
+			Id:         app.Id,
+			Global:     stateSchema(app.Params.GlobalStateSchema),
+			Local:      stateSchema(app.Params.LocalStateSchema),
+			ExtraPages: uint32(app.Params.ExtraProgramPages),
+		})
+	}
+	for _, asset := range info.CreatedAssets {
+		if !asset.Deleted {
+			v.CreatedAssets = append(v.CreatedAssets, asset.Index)
+		}
+	}
+	for _, local := range info.AppsLocalState {
+		if local.Deleted {
+			continue
+		}
+		v.LocalStates = append(v.LocalStates, LocalStateView{
+			AppId:   local.Id,
+			Schema:  stateSchema(local.Schema),
+			Used:    len(local.KeyValue),
+			OptedIn: local.OptedInAtRound,
+		})
+	}
+	for _, asset := range info.Assets {
+		if asset.Deleted {
+			continue
+		}
+		v.Assets = append(v.Assets, AssetView{
+			Id:      asset.AssetId,
+			Amount:  asset.Amount,
+			Creator: asset.Creator,
+			Frozen:  asset.IsFrozen,
+		})
+	}
+
+	// Closed accounts have no minimum balance
+	if info.Amount > 0 {
+		v.MinBalance = MinBalance{
+			Base:       BaseMinBalance,
+			Assets:     AssetMinBalance * uint64(len(v.Assets)),
+			Apps:       AppCreateMinBalance * uint64(len(v.CreatedApps)),
+			ExtraPages: AppPageMinBalance * info.AppsTotalExtraPages,
+			OptIns:     AppOptInMinBalance * uint64(len(v.LocalStates)),
+			Schema:     schemaMinBalance(stateSchema(info.AppsTotalSchema)),
+		}
+	}
+	if min := v.MinBalance.Total(); v.Balance > min {
+		v.Spendable = v.Balance - min
+	}
+	return v
+}
+
+// CanAfford checks the account can pay amount, fees included, while
+// its minimum balance rises by minDelta.
+func (v AccountView) CanAfford(amount, minDelta uint64) error {
+	if v.Spendable < amount+minDelta {
+		return fmt.Errorf(
+			"%s: %d spendable, %d needed (%d plus %d minimum balance): %w",
+			v.Address, v.Spendable, amount+minDelta, amount, minDelta, ErrInsufficientBalance,
+		)
+	}
+	return nil
+}
+
+// AppCreateCost is the minimum balance an app adds to its creator,
+// with optIn the creator's local state is included.
+func AppCreateCost(global, local types.StateSchema, extraPages uint32, optIn bool) uint64 {
+	cost := AppCreateMinBalance + AppPageMinBalance*uint64(extraPages) + schemaMinBalance(global)
+	if optIn {
+		cost += AppOptInMinBalance + schemaMinBalance(local)
+	}
+	return cost
+}
+
+func schemaMinBalance(s types.StateSchema) uint64 {
+	return (s.NumUint+s.NumByteSlice)*SchemaEntryMinBalance +
+		s.NumUint*SchemaUintMinBalance + s.NumByteSlice*SchemaBytesMinBalance
+}
+
+func stateSchema(s models.ApplicationStateSchema) types.StateSchema {
+	return types.StateSchema{NumUint: s.NumUint, NumByteSlice: s.NumByteSlice}
+}
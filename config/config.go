@@ -14,11 +14,51 @@ const (
 )
 
 type Setup struct {
-	Target     string `mapstructure:"type"`
-	Timeout    uint32 `mapstructure:"time"`
-	NodePath   string `mapstructure:"node"`
-	AssetPath  string `mapstructure:"data"`
-	Passphrase string `mapstructure:"pass"`
+	Target     string         `mapstructure:"type"`
+	Timeout    uint32         `mapstructure:"time"`
+	NodePath   string         `mapstructure:"node"`
+	AssetPath  string         `mapstructure:"data"`
+	Passphrase string         `mapstructure:"pass"`
+	Accounts   []AccountSetup `mapstructure:"accounts"`
+	Ports      PortSetup      `mapstructure:"ports"`
+	Genesis    GenesisSetup   `mapstructure:"genesis"`
+	Kdf        string         `mapstructure:"kdf"`
+	Rehash     bool           `mapstructure:"rehash"`
+	DevSeed    string         `mapstructure:"seed"`
+
+	Faucets map[string]FaucetSetup `mapstructure:"faucets"`
+}
+
+// FaucetSetup is the funding policy of a network target, keyed by the
+// target type. Url selects an http dispenser, zero values are no limit.
+// At most Requests fundings are made per Window seconds.
+type FaucetSetup struct {
+	Url       string `mapstructure:"url"`
+	Token     string `mapstructure:"token"`
+	MaxAmount uint64 `mapstructure:"max_amount"`
+	Requests  uint32 `mapstructure:"requests"`
+	Window    uint32 `mapstructure:"window"`
+}
+
+// GenesisSetup pins the network identity, Hash is base64 encoded.
+// Empty values use the known network or the genesis file.
+type GenesisSetup struct {
+	Id   string `mapstructure:"id"`
+	Hash string `mapstructure:"hash"`
+}
+
+// PortSetup holds the ports of a new network, zero picks a free port.
+type PortSetup struct {
+	Algod  uint16 `mapstructure:"algod"`
+	Kmd    uint16 `mapstructure:"kmd"`
+	Gossip uint16 `mapstructure:"gossip"`
+}
+
+// AccountSetup is a keystore account under <asset>/accounts that
+// is funded in the genesis of a private network.
+type AccountSetup struct {
+	Name    string `mapstructure:"name"`
+	Balance uint64 `mapstructure:"balance"`
 }
 
 type Config struct {
@@ -27,6 +67,13 @@ type Config struct {
 	NodePath  string
 	DataPath  string
 	AssetPath string
+	Accounts  []AccountSetup
+	Ports     PortSetup
+	Genesis   GenesisSetup
+	Kdf       string
+	Rehash    bool
+	DevSeed   string
+	Faucets   map[string]FaucetSetup
 }
 
 var cfg = Config{
@@ -53,10 +100,73 @@ func AssetPath() string {
 	return cfg.AssetPath
 }
 
+func GenesisAccounts() []AccountSetup {
+	return cfg.Accounts
+}
+
+func Ports() PortSetup {
+	return cfg.Ports
+}
+
+func Genesis() GenesisSetup {
+	return cfg.Genesis
+}
+
+// Kdf returns the keystore key derivation profile, by default
+// "fast" for devnet, "standard" for testnet and "paranoid" for mainnet.
+func Kdf() string {
+	if len(cfg.Kdf) > 0 {
+		return cfg.Kdf
+	}
+	switch cfg.Target {
+	case Testnet:
+		return "standard"
+	case Mainnet:
+		return "paranoid"
+	default:
+		return "fast"
+	}
+}
+
+// Rehash reports whether weaker keystores are rehashed on load, a
+// failed rehash fails the load.
+func Rehash() bool {
+	return cfg.Rehash
+}
+
+// DevSeed returns the seed of derived devnet accounts.
+func DevSeed() string {
+	return cfg.DevSeed
+}
+
+// Faucet returns the funding policy of the target.
+func Faucet() FaucetSetup {
+	return cfg.Faucets[TargetName()]
+}
+
+// TargetName returns the type name of the target.
+func TargetName() string {
+	switch cfg.Target {
+	case Devnet:
+		return "devnet"
+	case Testnet:
+		return "testnet"
+	case Mainnet:
+		return "mainnet"
+	}
+	return ""
+}
+
 func OnCreate(s Setup) error {
 	if s.Timeout > 16 {
 		cfg.Timeout = s.Timeout
 	}
+	cfg.Ports = s.Ports
+	cfg.Genesis = s.Genesis
+	cfg.Kdf = s.Kdf
+	cfg.Rehash = s.Rehash
+	cfg.DevSeed = s.DevSeed
+	cfg.Faucets = s.Faucets
 
 	switch s.Target {
 	case "devnet":
@@ -76,6 +186,12 @@ func OnInitialize(s Setup, validate bool) error {
 	if s.Timeout > 16 {
 		cfg.Timeout = s.Timeout
 	}
+	cfg.Ports = s.Ports
+	cfg.Genesis = s.Genesis
+	cfg.Kdf = s.Kdf
+	cfg.Rehash = s.Rehash
+	cfg.DevSeed = s.DevSeed
+	cfg.Faucets = s.Faucets
 
 	switch s.Target {
 	case "devnet":
@@ -97,6 +213,21 @@ func OnInitialize(s Setup, validate bool) error {
 		return fmt.Errorf("init config: invalid node path: %s", err)
 	}
 
+	cfg.Accounts = s.Accounts
+	for _, a := range cfg.Accounts {
+		if len(a.Name) == 0 || a.Balance == 0 {
+			return fmt.Errorf("init config: invalid account: %q", a.Name)
+		}
+	}
+
+	for name := range cfg.Faucets {
+		switch name {
+		case "devnet", "testnet", "mainnet":
+		default:
+			return fmt.Errorf("init config: invalid faucet target: %s", name)
+		}
+	}
+
 	cfg.AssetPath = s.AssetPath
 	if err := IsAssetPath(cfg.AssetPath, cfg.Target); nil != err {
 		return fmt.Errorf("init config: invalid asset path: %s", err)
@@ -4,6 +4,7 @@ type NodeConfig struct {
 	Version            uint64
 	GossipFanout       uint64
 	NetAddress         string
+	EndpointAddress    string
 	DNSBootstrapID     string
 	EnableProfiler     bool
 	EnableDeveloperAPI bool
@@ -17,15 +17,25 @@ import (
 func Build(list []string) error {
 	fmt.Println(":: Contracts build:", cfg.DataPath())
 
-	for _, s := range list {
-		if err := build(s); nil != err {
-			return err
+	return net.WithLock(net.LockShared, func() error {
+		caps, err := net.Capabilities()
+		if err != nil {
+			return fmt.Errorf("build failed: %s", err)
 		}
-		if err := compile(s); nil != err {
-			return err
+		if err := caps.RequireDeveloperAPI(); nil != err {
+			return fmt.Errorf("build failed: %w", err)
 		}
-	}
-	return nil
+
+		for _, s := range list {
+			if err := build(s); nil != err {
+				return err
+			}
+			if err := compile(s, caps); nil != err {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func build(name string) error {
@@ -42,7 +52,7 @@ func build(name string) error {
 	return nil
 }
 
-func compile(name string) error {
+func compile(name string, caps net.NodeCapabilities) error {
 	cln, err := net.MakeClient()
 	if err != nil {
 		return fmt.Errorf("compile %s failed: make client: %s", name, err)
@@ -64,6 +74,9 @@ func compile(name string) error {
 	if err != nil {
 		return fmt.Errorf("compile %s failed: decode program: %s", name, err)
 	}
+	if err = caps.RequireProgram(prg); nil != err {
+		return fmt.Errorf("compile %s failed: %w", name, err)
+	}
 	err = logic.CheckProgram(prg, make([][]byte, 0))
 	if nil != err {
 		return fmt.Errorf("compile %s failed: check program: %s", name, err)
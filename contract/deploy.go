@@ -1,22 +1,34 @@
 package contract
 
 import (
-	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 
+	acc "github.com/vecno-io/go-pyteal/account"
 	cfg "github.com/vecno-io/go-pyteal/config"
 	net "github.com/vecno-io/go-pyteal/network"
 
-	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
 	"github.com/algorand/go-algorand-sdk/future"
 	"github.com/algorand/go-algorand-sdk/types"
 )
 
+// Setup describes an application to deploy. Manager signs the create
+// tx and its address is the sender unless Sender is set. A rekeyed
+// sender is signed by its stored auth account, Passphrase unlocks it.
+// RekeyTo rekeys the sender with the create tx.
 type Setup struct {
-	Manager crypto.Account
+	Manager acc.Signer
+	Sender  types.Address
+	Params  net.ParamsOptions
+
+	RekeyTo    types.Address
+	Passphrase acc.PassFunc
 
 	ClearProg    string
 	ApprovalProg string
@@ -25,93 +37,199 @@ type Setup struct {
 	GlobalSchema types.StateSchema
 }
 
+// Record is a deployed application with the network it was deployed to,
+// the genesis hash is base64 encoded.
+type Record struct {
+	Id          uint64 `json:"id"`
+	GenesisId   string `json:"genesis_id"`
+	GenesisHash string `json:"genesis_hash"`
+}
+
 func GetId(name string) (uint64, error) {
+	rec, err := loadFromJsonFile(name)
+	return rec.Id, err
+}
+
+func GetRecord(name string) (Record, error) {
 	return loadFromJsonFile(name)
 }
 
 func Deploy(s Setup) error {
-	optIn := true
+	return net.WithLock(net.LockShared, func() error {
+		optIn := true
 
-	if _, err := os.Stat(fmt.Sprintf(
-		"%s/%s.id",
-		cfg.AssetPath(), s.ApprovalProg),
-	); nil == err {
-		return fmt.Errorf("deploy: %s is already deployed", s.ApprovalProg)
-	}
-	fmt.Println(":: Deploy contract build:", s.ApprovalProg)
+		if _, err := os.Stat(fmt.Sprintf(
+			"%s/%s.id",
+			cfg.AssetPath(), s.ApprovalProg),
+		); nil == err {
+			return fmt.Errorf("deploy: %s is already deployed", s.ApprovalProg)
+		}
+		fmt.Println(":: Deploy contract build:", s.ApprovalProg)
 
-	clearProg, err := ioutil.ReadFile(fmt.Sprintf(
-		"%s/contracts/%s.prog", cfg.AssetPath(), s.ClearProg,
-	))
-	if err != nil {
-		return fmt.Errorf("deploy failed: %s: read file: %s", s.ClearProg, err)
-	}
-	approvalProg, err := ioutil.ReadFile(fmt.Sprintf(
-		"%s/contracts/%s.prog", cfg.AssetPath(), s.ApprovalProg,
-	))
-	if err != nil {
-		return fmt.Errorf("deploy failed: %s: read file: %s", s.ApprovalProg, err)
-	}
+		clearProg, err := ioutil.ReadFile(fmt.Sprintf(
+			"%s/contracts/%s.prog", cfg.AssetPath(), s.ClearProg,
+		))
+		if err != nil {
+			return fmt.Errorf("deploy failed: %s: read file: %s", s.ClearProg, err)
+		}
+		approvalProg, err := ioutil.ReadFile(fmt.Sprintf(
+			"%s/contracts/%s.prog", cfg.AssetPath(), s.ApprovalProg,
+		))
+		if err != nil {
+			return fmt.Errorf("deploy failed: %s: read file: %s", s.ApprovalProg, err)
+		}
 
-	appArgs := [][]byte{}
-	accounts := []string{}
-	foreignApps := []uint64{}
-	foreignAssets := []uint64{}
+		appArgs := [][]byte{}
+		accounts := []string{}
+		foreignApps := []uint64{}
+		foreignAssets := []uint64{}
 
-	cln, err := net.MakeClient()
-	if err != nil {
-		return fmt.Errorf("deploy failed: make client: %s", err)
-	}
-	txnParams, err := cln.SuggestedParams().Do(context.Background())
-	if err != nil {
-		return fmt.Errorf("deploy failed: suggested params: %s", err)
-	}
+		caps, err := net.Capabilities()
+		if err != nil {
+			return fmt.Errorf("deploy failed: %s", err)
+		}
+		for _, prog := range [][]byte{approvalProg, clearProg} {
+			if err := caps.RequireProgram(prog); nil != err {
+				return fmt.Errorf("deploy failed: %w", err)
+			}
+		}
 
-	note := []byte{}
-	group := types.Digest{}
-	lease := [32]byte{}
-	rekeyTo := types.ZeroAddress
-	extraPages := uint32(0)
-
-	createTx, err := future.MakeApplicationCreateTxWithExtraPages(
-		optIn, approvalProg, clearProg, s.GlobalSchema, s.LocalSchema,
-		appArgs, accounts, foreignApps, foreignAssets, txnParams,
-		s.Manager.Address, note, group, lease, rekeyTo, extraPages,
-	)
-	if err != nil {
-		return fmt.Errorf("deploy failed: make create tx: %s", err)
-	}
+		cln, err := net.MakeClient()
+		if err != nil {
+			return fmt.Errorf("deploy failed: make client: %s", err)
+		}
+		txnParams, err := net.MakeTxnParamsWithClient(cln, s.Params)
+		if err != nil {
+			return fmt.Errorf("deploy failed: suggested params: %s", err)
+		}
 
-	// Enforce it or fail, a bug?
-	createTx.OnCompletion = types.OptInOC
+		note := []byte{}
+		group := types.Digest{}
+		lease := [32]byte{}
+		rekeyTo := s.RekeyTo
+		extraPages := uint32(0)
+		if err := caps.RequireExtraPages(extraPages); nil != err {
+			return fmt.Errorf("deploy failed: %w", err)
+		}
 
-	_, signedTx, err := crypto.SignTransaction(s.Manager.PrivateKey, createTx)
+		sender := s.Sender
+		if sender.IsZero() {
+			if nil == s.Manager {
+				return fmt.Errorf("deploy failed: no sender or manager")
+			}
+			sender = s.Manager.Address()
+		}
+
+		createTx, err := future.MakeApplicationCreateTxWithExtraPages(
+			optIn, approvalProg, clearProg, s.GlobalSchema, s.LocalSchema,
+			appArgs, accounts, foreignApps, foreignAssets, txnParams,
+			sender, note, group, lease, rekeyTo, extraPages,
+		)
+		if err != nil {
+			return fmt.Errorf("deploy failed: make create tx: %s", err)
+		}
+
+		// Enforce it or fail, a bug?
+		createTx.OnCompletion = types.OptInOC
+		createTx = net.ApplyMinFee(createTx, txnParams)
+
+		view, err := acc.View(sender.String())
+		if err != nil {
+			return fmt.Errorf("deploy failed: %s", err)
+		}
+		cost := acc.AppCreateCost(s.GlobalSchema, s.LocalSchema, extraPages, optIn)
+		if err := view.CanAfford(uint64(createTx.Fee), cost); nil != err {
+			return fmt.Errorf("deploy failed: %w", err)
+		}
+
+		signedTx, err := s.sign(createTx)
+		if errors.Is(err, acc.ErrPartiallySigned) {
+			if err := acc.SavePartial(s.ApprovalProg, signedTx); nil != err {
+				return fmt.Errorf("deploy failed: %s", err)
+			}
+			fmt.Println(">> Partial create tx saved:", acc.PartialPath(s.ApprovalProg))
+			return fmt.Errorf("deploy: sign create tx: %w", err)
+		}
+		if err != nil {
+			return fmt.Errorf("deploy failed: sign create tx: %w", err)
+		}
+
+		return submit(cln, s.ApprovalProg, signedTx)
+	})
+}
+
+// CompleteDeploy submits a multisig deploy once all members signed
+// the partial create tx saved by Deploy.
+func CompleteDeploy(name string) error {
+	fmt.Println(":: Complete contract deploy:", name)
+
+	return net.WithLock(net.LockShared, func() error {
+		ptx, err := acc.LoadPartial(name)
+		if err != nil {
+			return fmt.Errorf("deploy failed: %s", err)
+		}
+		count, threshold, err := acc.PartialStatus(ptx)
+		if err != nil {
+			return fmt.Errorf("deploy failed: %s", err)
+		}
+		if count < threshold {
+			return fmt.Errorf("deploy: %d of %d signatures: %w", count, threshold, acc.ErrPartiallySigned)
+		}
+
+		cln, err := net.MakeClient()
+		if err != nil {
+			return fmt.Errorf("deploy failed: make client: %s", err)
+		}
+		if err := submit(cln, name, ptx); nil != err {
+			return err
+		}
+		return os.Remove(acc.PartialPath(name))
+	})
+}
+
+func (s Setup) sign(tx types.Transaction) ([]byte, error) {
+	auth, err := acc.AuthAddress(tx.Sender.String())
 	if err != nil {
-		return fmt.Errorf("deploy failed: sign create tx: %s", err)
+		return nil, err
+	}
+	if nil != s.Manager && auth == s.Manager.Address().String() {
+		return s.Manager.SignTransaction(tx)
 	}
-	pendingTx, err := cln.SendRawTransaction(signedTx).Do(context.Background())
+	if nil == s.Passphrase {
+		return nil, fmt.Errorf("sender signs with %s, no passphrase: %w", auth, acc.ErrSignerUnavailable)
+	}
+	signer, err := acc.ResolveSigner(tx.Sender.String(), s.Passphrase)
 	if err != nil {
-		return fmt.Errorf("deploy failed: send create tx: %s", err)
+		return nil, err
 	}
+	return signer.SignTransaction(tx)
+}
 
-	txConfirm, err := net.WaitForConfirmation(cln, pendingTx, 24, context.Background())
-	if err != nil {
-		return fmt.Errorf("deploy failed: confirm tx: %s", err)
+func submit(cln *algod.Client, name string, signedTx []byte) error {
+	stx := types.SignedTxn{}
+	if err := msgpack.Decode(signedTx, &stx); nil != err {
+		return fmt.Errorf("deploy failed: decode create tx: %s", err)
 	}
-	if len(txConfirm.PoolError) > 0 {
-		return fmt.Errorf("deploy failed: pool error: %s", txConfirm.PoolError)
+
+	res, err := net.SendWithClient(cln, signedTx, net.DefaultSendOptions())
+	if err != nil {
+		return fmt.Errorf("deploy failed: send create tx: %w", err)
 	}
 
-	fmt.Printf(">> App deployed with id: %d\n", txConfirm.ApplicationIndex)
-	if err := saveToFile(s.ApprovalProg, txConfirm.ApplicationIndex); err != nil {
+	fmt.Printf(">> App deployed with id: %d\n", res.Info.ApplicationIndex)
+	if err := saveToFile(name, Record{
+		Id:          res.Info.ApplicationIndex,
+		GenesisId:   stx.Txn.GenesisID,
+		GenesisHash: base64.StdEncoding.EncodeToString(stx.Txn.GenesisHash[:]),
+	}); err != nil {
 		return fmt.Errorf("contract: failed to save app: %s", err)
 	}
 
 	return nil
 }
 
-func saveToFile(name string, id uint64) error {
-	str, err := json.Marshal(id)
+func saveToFile(name string, rec Record) error {
+	str, err := json.Marshal(rec)
 	if err != nil {
 		return err
 	}
@@ -124,18 +242,22 @@ func saveToFile(name string, id uint64) error {
 	return nil
 }
 
-func loadFromJsonFile(name string) (uint64, error) {
-	id := uint64(0)
+func loadFromJsonFile(name string) (Record, error) {
+	rec := Record{}
 	// TODO Fix Path
 	data, err := os.ReadFile(fmt.Sprintf(
 		"%s/%s.id", cfg.AssetPath(), name,
 	))
 	if err != nil {
-		return 0, err
+		return Record{}, err
+	}
+	// Older records hold the bare id only
+	if err = json.Unmarshal(data[:], &rec.Id); nil == err {
+		return rec, nil
 	}
-	err = json.Unmarshal(data[:], &id)
+	err = json.Unmarshal(data[:], &rec)
 	if err != nil {
-		return 0, err
+		return Record{}, err
 	}
-	return id, nil
+	return rec, nil
 }
@@ -11,5 +11,6 @@ require (
 require (
 	github.com/algorand/go-codec/codec v1.1.7 // indirect
 	github.com/google/go-querystring v1.0.0 // indirect
+	golang.org/x/sys v0.0.0-20190412213103-97732733099d // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 )
@@ -0,0 +1,211 @@
+package net
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+)
+
+// BatchOptions controls a batch submission, InFlight limits the number
+// of transactions that are sent but not yet confirmed (default 16).
+// WaitRounds counts from the round a transaction was sent in.
+type BatchOptions struct {
+	InFlight   int
+	WaitRounds uint64
+	MaxWait    time.Duration
+}
+
+// BatchResult holds the outcome of a single transaction in a batch.
+type BatchResult struct {
+	TxId string
+	Info models.PendingTransactionInfoResponse
+	Err  error
+}
+
+func DefaultBatchOptions() BatchOptions {
+	return BatchOptions{
+		InFlight:   16,
+		WaitRounds: 24,
+	}
+}
+
+// SendBatch submits signed transactions concurrently and tracks them in a
+// single status loop, results are returned in input order. A failure of
+// one transaction is reported in its result and does not stop the others.
+func SendBatch(txns [][]byte, opts BatchOptions) ([]BatchResult, error) {
+	cln, err := MakeClient()
+	if err != nil {
+		return nil, fmt.Errorf("make client: %s", err)
+	}
+	return SendBatchWithClient(cln, txns, opts)
+}
+
+func SendBatchWithClient(cln *algod.Client, txns [][]byte, opts BatchOptions) ([]BatchResult, error) {
+	if opts.InFlight <= 0 {
+		opts.InFlight = DefaultBatchOptions().InFlight
+	}
+	ctx := context.Background()
+	if opts.MaxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.MaxWait)
+		defer cancel()
+	}
+
+	status, err := cln.Status().Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("client status: %s", err)
+	}
+
+	b := batch{
+		round:   status.LastRound,
+		results: make([]BatchResult, len(txns)),
+		pending: make(map[int]uint64),
+		slots:   make(chan struct{}, opts.InFlight),
+	}
+
+	var senders sync.WaitGroup
+	senders.Add(1)
+	go func() {
+		defer senders.Done()
+		for i, txn := range txns {
+			select {
+			case b.slots <- struct{}{}:
+			case <-ctx.Done():
+				b.fail(i, fmt.Errorf("client send: %w", ErrTimeout))
+				continue
+			}
+			senders.Add(1)
+			go func(i int, txn []byte) {
+				defer senders.Done()
+				txid, err := cln.SendRawTransaction(txn).Do(ctx)
+				if err != nil {
+					b.fail(i, fmt.Errorf("client send: %w", sendError(err)))
+					<-b.slots
+					return
+				}
+				b.add(i, txid)
+			}(i, txn)
+		}
+	}()
+
+	sent := make(chan struct{})
+	go func() {
+		senders.Wait()
+		close(sent)
+	}()
+
+	for {
+		done := false
+		select {
+		case <-sent:
+			done = true
+		default:
+		}
+		for i, round := range b.tracked() {
+			b.check(ctx, cln, i, round, opts.WaitRounds)
+		}
+		if done && len(b.tracked()) == 0 {
+			return b.results, nil
+		}
+		if nil != ctx.Err() {
+			<-sent
+			for i := range b.tracked() {
+				b.fail(i, fmt.Errorf("client wait: %w", ErrTimeout))
+			}
+			return b.results, nil
+		}
+		// Wait for the next round, or briefly while nothing is pending
+		if len(b.tracked()) == 0 {
+			select {
+			case <-sent:
+			case <-time.After(100 * time.Millisecond):
+			}
+			continue
+		}
+		status, err := cln.StatusAfterBlock(b.next()).Do(ctx)
+		if err == nil {
+			b.setRound(status.LastRound)
+		}
+	}
+}
+
+type batch struct {
+	mu      sync.Mutex
+	round   uint64
+	results []BatchResult
+	pending map[int]uint64
+	slots   chan struct{}
+}
+
+func (b *batch) add(i int, txid string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.results[i].TxId = txid
+	b.pending[i] = b.round
+}
+
+func (b *batch) fail(i int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.results[i].Err = err
+	if _, ok := b.pending[i]; ok {
+		delete(b.pending, i)
+		<-b.slots
+	}
+}
+
+func (b *batch) finish(i int, info models.PendingTransactionInfoResponse) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.results[i].Info = info
+	delete(b.pending, i)
+	<-b.slots
+}
+
+func (b *batch) tracked() map[int]uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	list := make(map[int]uint64, len(b.pending))
+	for i, round := range b.pending {
+		list[i] = round
+	}
+	return list
+}
+
+func (b *batch) next() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.round + 1
+}
+
+func (b *batch) setRound(round uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if round > b.round {
+		b.round = round
+	}
+}
+
+func (b *batch) check(ctx context.Context, cln *algod.Client, i int, sent, waitRounds uint64) {
+	b.mu.Lock()
+	txid, round := b.results[i].TxId, b.round
+	b.mu.Unlock()
+
+	info, _, err := cln.PendingTransactionInformation(txid).Do(ctx)
+	switch {
+	case err != nil:
+		if nil == ctx.Err() {
+			b.fail(i, fmt.Errorf("client wait: %s", err))
+		}
+	case info.ConfirmedRound > 0:
+		b.finish(i, info)
+	case len(info.PoolError) > 0:
+		b.fail(i, fmt.Errorf("client wait: %w", newPoolError(info.PoolError)))
+	case waitRounds > 0 && round > sent+waitRounds:
+		b.fail(i, fmt.Errorf("wait for transaction id %s: %w", txid, ErrTimeout))
+	}
+}
@@ -2,30 +2,43 @@ package net
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 	"strings"
+	"time"
 
 	cfg "github.com/vecno-io/go-pyteal/config"
 
+	"github.com/algorand/go-algorand-sdk/client/kmd"
 	"github.com/algorand/go-algorand-sdk/client/v2/algod"
 	"github.com/algorand/go-algorand-sdk/client/v2/common"
 	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
 	"github.com/algorand/go-algorand-sdk/types"
 )
 
-func MakeClient() (*algod.Client, error) {
-	path := cfg.DataPath()
-	// Fix hard coded sub path
-	if cfg.Devnet == cfg.Target() {
-		path += "/primary"
-	}
+func MakeClient() (cln *algod.Client, err error) {
+	err = WithLock(LockShared, func() error {
+		cln, err = makeClient()
+		return err
+	})
+	return cln, err
+}
 
+func makeClient() (*algod.Client, error) {
+	name, path := primaryNode()
+
+	// The net file is written on start, fall back to the assigned port
 	addr, err := getFirstLineFromFile(fmt.Sprintf(
 		"%s/algod.net", path,
 	))
 	if err != nil {
-		return nil, fmt.Errorf("read network file: %s", err)
+		ports, perr := LoadPorts()
+		if perr != nil || ports[name].Algod == 0 {
+			return nil, fmt.Errorf("read network file: %s", err)
+		}
+		addr = fmt.Sprintf("127.0.0.1:%d", ports[name].Algod)
 	}
 
 	token, err := getFirstLineFromFile(fmt.Sprintf(
@@ -37,47 +50,119 @@ func MakeClient() (*algod.Client, error) {
 	return algod.MakeClient("http://"+addr, token)
 }
 
-func MakeTxnParams() (types.SuggestedParams, error) {
-	cln, err := MakeClient()
+func MakeKmdClient() (cln kmd.Client, err error) {
+	err = WithLock(LockShared, func() error {
+		cln, err = makeKmdClient()
+		return err
+	})
+	return cln, err
+}
+
+func makeKmdClient() (kmd.Client, error) {
+	name, path := primaryNode()
+	path, err := kmdPath(path)
+	if err != nil {
+		return kmd.Client{}, fmt.Errorf("kmd path: %s", err)
+	}
+
+	addr, err := getFirstLineFromFile(fmt.Sprintf(
+		"%s/kmd.net", path,
+	))
 	if err != nil {
-		return types.SuggestedParams{}, fmt.Errorf("make client: %s", err)
+		ports, perr := LoadPorts()
+		if perr != nil || ports[name].Kmd == 0 {
+			return kmd.Client{}, fmt.Errorf("read network file: %s", err)
+		}
+		addr = fmt.Sprintf("127.0.0.1:%d", ports[name].Kmd)
 	}
 
-	txnParams, err := cln.SuggestedParams().Do(context.Background())
+	token, err := getFirstLineFromFile(fmt.Sprintf(
+		"%s/kmd.token", path,
+	))
 	if err != nil {
-		return types.SuggestedParams{}, fmt.Errorf("suggested params: %s", err)
+		return kmd.Client{}, fmt.Errorf("read token file: %s", err)
+	}
+	return kmd.MakeClient("http://"+addr, token)
+}
+
+// primaryNode returns the name and path of the node used by the clients.
+func primaryNode() (string, string) {
+	// Fix hard coded sub path
+	if cfg.Devnet == cfg.Target() {
+		return "primary", cfg.DataPath() + "/primary"
+	}
+	return filepath.Base(cfg.DataPath()), cfg.DataPath()
+}
+
+func MakeTxnParams() (types.SuggestedParams, error) {
+	return MakeTxnParamsWith(ParamsOptions{})
+}
+
+// SendOptions controls how a transaction is submitted.
+// WaitRounds and MaxWait of zero do not limit the wait.
+type SendOptions struct {
+	NoWait     bool
+	WaitRounds uint64
+	MaxWait    time.Duration
+}
+
+// SendResult holds the id and the last pending info of a transaction,
+// Info is empty when submitted with NoWait.
+type SendResult struct {
+	TxId string
+	Info models.PendingTransactionInfoResponse
+}
+
+func DefaultSendOptions() SendOptions {
+	return SendOptions{
+		WaitRounds: 24,
 	}
-	return txnParams, nil
 }
 
 func SendRawTransaction(txn []byte) (txInfo models.PendingTransactionInfoResponse, err error) {
+	res, err := Send(txn, DefaultSendOptions())
+	return res.Info, err
+}
+
+func Send(txn []byte, opts SendOptions) (SendResult, error) {
 	cln, err := MakeClient()
 	if err != nil {
-		err = fmt.Errorf("make client: %s", err)
-		return
+		return SendResult{}, fmt.Errorf("make client: %s", err)
 	}
+	return SendWithClient(cln, txn, opts)
+}
 
-	pendingTxID, err := cln.SendRawTransaction(txn).Do(context.Background())
-	if err != nil {
-		err = fmt.Errorf("client send: %s", err)
-		return
+func SendWithClient(cln *algod.Client, txn []byte, opts SendOptions) (res SendResult, err error) {
+	ctx := context.Background()
+	if opts.MaxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.MaxWait)
+		defer cancel()
 	}
 
-	txInfo, err = WaitForConfirmation(cln, pendingTxID, 24, context.Background())
+	res.TxId, err = cln.SendRawTransaction(txn).Do(ctx)
 	if err != nil {
-		err = fmt.Errorf("client wait: %s", err)
+		err = fmt.Errorf("client send: %w", sendError(err))
 		return
 	}
-	if len(txInfo.PoolError) > 0 {
-		err = fmt.Errorf("txn pool: %s", err)
+	if opts.NoWait {
+		return
+	}
+
+	res.Info, err = WaitForConfirmation(cln, res.TxId, opts.WaitRounds, ctx)
+	if err != nil {
+		err = fmt.Errorf("client wait: %w", err)
 		return
 	}
 	return
 }
 
+// WaitForConfirmation waits for txid to be confirmed, it returns ErrTimeout
+// when waitRounds pass or ctx expires and a *PoolError when the node drops it.
 func WaitForConfirmation(c *algod.Client, txid string, waitRounds uint64, ctx context.Context, headers ...*common.Header) (txInfo models.PendingTransactionInfoResponse, err error) {
 	response, err := c.Status().Do(ctx, headers...)
 	if err != nil {
+		err = waitError(ctx, txid, err)
 		return
 	}
 
@@ -87,20 +172,27 @@ func WaitForConfirmation(c *algod.Client, txid string, waitRounds uint64, ctx co
 	for {
 		// Check that the `waitRounds` has not passed
 		if waitRounds > 0 && currentRound > lastRound+waitRounds {
-			err = fmt.Errorf("wait for transaction id %s timed out", txid)
+			err = fmt.Errorf("wait for transaction id %s: %w", txid, ErrTimeout)
 			return
 		}
 		txInfo, _, err = c.PendingTransactionInformation(txid).Do(ctx, headers...)
 		if err != nil {
+			err = waitError(ctx, txid, err)
 			return
 		}
 		// The transaction has been confirmed
 		if txInfo.ConfirmedRound > 0 {
 			return
 		}
+		// The transaction was removed from the pool
+		if len(txInfo.PoolError) > 0 {
+			err = newPoolError(txInfo.PoolError)
+			return
+		}
 		// Wait until the block for the `currentRound` is confirmed
 		response, err = c.StatusAfterBlock(currentRound).Do(ctx, headers...)
 		if err != nil {
+			err = waitError(ctx, txid, err)
 			return
 		}
 		// Increment the `currentRound`
@@ -108,6 +200,22 @@ func WaitForConfirmation(c *algod.Client, txid string, waitRounds uint64, ctx co
 	}
 }
 
+// sendError classifies a rejection of the node on submission.
+func sendError(err error) error {
+	if strings.HasPrefix(err.Error(), "HTTP 400") {
+		return newPoolError(err.Error())
+	}
+	return err
+}
+
+// waitError maps an expired context to ErrTimeout.
+func waitError(ctx context.Context, txid string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("wait for transaction id %s: %w", txid, ErrTimeout)
+	}
+	return err
+}
+
 func getFirstLineFromFile(file string) (string, error) {
 	addrStr, err := ioutil.ReadFile(file)
 	if err != nil {
@@ -0,0 +1,104 @@
+package net
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+)
+
+// MarkerFile marks a data path as created by go-pyteal.
+const MarkerFile = ".go-pyteal"
+
+// DestroyOptions controls Destroy. DryRun only lists what would be
+// removed, Force is required to remove a testnet or mainnet data path
+// and data paths without a marker file.
+type DestroyOptions struct {
+	DryRun bool
+	Force  bool
+}
+
+func writeMarker() error {
+	return os.WriteFile(
+		filepath.Join(cfg.DataPath(), MarkerFile),
+		[]byte(fmt.Sprintf("%d %s\n", cfg.Target(), time.Now().UTC().Format(time.RFC3339))),
+		0644,
+	)
+}
+
+// ErrNoMarker is returned for data paths without a marker file, they
+// were created before the marker existed or not by go-pyteal.
+var ErrNoMarker = errors.New("no go-pyteal marker")
+
+// IsDataPath checks that the data path was created by go-pyteal for
+// the configured target, the marker file has to be present.
+func IsDataPath() error {
+	info, err := os.Stat(cfg.DataPath())
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", cfg.DataPath())
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.DataPath(), MarkerFile))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrNoMarker, cfg.DataPath())
+	}
+	if err != nil {
+		return fmt.Errorf("marker: %s", err)
+	}
+	target := cfg.Network(0)
+	if _, err := fmt.Sscanf(string(data), "%d", &target); nil != err {
+		return fmt.Errorf("marker: invalid content: %s", err)
+	}
+	if target != cfg.Target() {
+		return fmt.Errorf("marker: created for network %d, target is %d", target, cfg.Target())
+	}
+	return nil
+}
+
+// isLegacyDataPath accepts a data path without marker by its layout,
+// only used when the caller forces the operation.
+func isLegacyDataPath() error {
+	if err := cfg.IsNetworkPath(cfg.DataPath(), cfg.Target()); nil != err {
+		return fmt.Errorf("not a go-pyteal data path: %s", err)
+	}
+	fmt.Println(">> warning: no marker in", cfg.DataPath(), "accepted by its layout")
+	return nil
+}
+
+// printDataPath lists the entries below the data path with their size.
+func printDataPath() error {
+	list, err := os.ReadDir(cfg.DataPath())
+	if err != nil {
+		return err
+	}
+	total := int64(0)
+	for _, entry := range list {
+		size, err := pathSize(filepath.Join(cfg.DataPath(), entry.Name()))
+		if err != nil {
+			return err
+		}
+		total += size
+		fmt.Printf(">> remove %s (%d bytes)\n", filepath.Join(cfg.DataPath(), entry.Name()), size)
+	}
+	fmt.Printf(">> remove %s (%d bytes total)\n", cfg.DataPath(), total)
+	return nil
+}
+
+func pathSize(path string) (int64, error) {
+	size := int64(0)
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,88 @@
+package net
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+)
+
+// setupDataPath points the config to a devnet with a primary node.
+func setupDataPath(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	node, asset := filepath.Join(dir, "node"), filepath.Join(dir, "asset")
+	for _, d := range []string{filepath.Join(node, "devnet-data", "primary"), filepath.Join(asset, "images"), filepath.Join(asset, "contracts")} {
+		if err := os.MkdirAll(d, 0700); nil != err {
+			t.Fatal(err)
+		}
+	}
+	for _, bin := range []string{"algod", "goal", "kmd"} {
+		if err := os.WriteFile(filepath.Join(node, bin), nil, 0700); nil != err {
+			t.Fatal(err)
+		}
+	}
+	err := cfg.OnInitialize(cfg.Setup{Target: "devnet", NodePath: node, AssetPath: asset}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cfg.OnCreate(cfg.Setup{Target: "devnet"})
+	})
+	for _, f := range []string{"config.json", "genesis.json"} {
+		if err := os.WriteFile(filepath.Join(cfg.DataPath(), "primary", f), []byte("{}"), 0644); nil != err {
+			t.Fatal(err)
+		}
+	}
+	return cfg.DataPath()
+}
+
+func TestIsDataPath(t *testing.T) {
+	path := setupDataPath(t)
+	if err := IsDataPath(); !errors.Is(err, ErrNoMarker) {
+		t.Fatalf("without marker: expected ErrNoMarker, got %v", err)
+	}
+
+	if err := writeMarker(); nil != err {
+		t.Fatal(err)
+	}
+	if err := IsDataPath(); nil != err {
+		t.Fatalf("with marker: %s", err)
+	}
+
+	marker := filepath.Join(path, MarkerFile)
+	if err := os.WriteFile(marker, []byte("2 2021-01-01T00:00:00Z\n"), 0644); nil != err {
+		t.Fatal(err)
+	}
+	if err := IsDataPath(); nil == err {
+		t.Fatal("testnet marker accepted for devnet")
+	}
+	if err := os.WriteFile(marker, []byte("invalid\n"), 0644); nil != err {
+		t.Fatal(err)
+	}
+	if err := IsDataPath(); nil == err {
+		t.Fatal("invalid marker accepted")
+	}
+}
+
+func TestDestroyRequiresMarker(t *testing.T) {
+	path := setupDataPath(t)
+	if err := Destroy(DestroyOptions{}); nil == err {
+		t.Fatal("destroyed a data path without marker")
+	}
+	if _, err := os.Stat(filepath.Join(path, "primary", "genesis.json")); nil != err {
+		t.Fatalf("data path changed: %s", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(path, MarkerFile), []byte("4 2021-01-01T00:00:00Z\n"), 0644); nil != err {
+		t.Fatal(err)
+	}
+	if err := Destroy(DestroyOptions{Force: true}); nil == err {
+		t.Fatal("destroyed a data path marked for another network")
+	}
+	if _, err := os.Stat(filepath.Join(path, "primary", "genesis.json")); nil != err {
+		t.Fatalf("data path changed: %s", err)
+	}
+}
@@ -0,0 +1,77 @@
+package net
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrTimeout is returned when a transaction is not confirmed
+// within the configured number of rounds or wall time.
+var ErrTimeout = errors.New("confirmation timed out")
+
+// ErrPoolRejected matches any PoolError, use errors.As to get the message.
+var ErrPoolRejected = errors.New("rejected by transaction pool")
+
+// ErrOverspend is the reason for a rejection on insufficient funds.
+var ErrOverspend = errors.New("overspend")
+
+// ErrLogicEval matches any LogicEvalError, use errors.As to get the pc.
+var ErrLogicEval = errors.New("logic eval error")
+
+// PoolError is returned when the node refuses or drops a transaction.
+// Reason holds the classified cause when known, ErrOverspend or a
+// *LogicEvalError, and is nil otherwise.
+type PoolError struct {
+	Message string
+	Reason  error
+}
+
+func (e *PoolError) Error() string {
+	return fmt.Sprintf("txn pool: %s", e.Message)
+}
+
+func (e *PoolError) Is(target error) bool {
+	return target == ErrPoolRejected
+}
+
+func (e *PoolError) Unwrap() error {
+	return e.Reason
+}
+
+// LogicEvalError is the reason for a rejection by a TEAL program.
+type LogicEvalError struct {
+	Pc      uint64
+	Message string
+}
+
+func (e *LogicEvalError) Error() string {
+	return fmt.Sprintf("logic eval error at pc=%d: %s", e.Pc, e.Message)
+}
+
+func (e *LogicEvalError) Is(target error) bool {
+	return target == ErrLogicEval
+}
+
+var pcExpr = regexp.MustCompile(`pc=(\d+)`)
+
+// newPoolError classifies a pool or send error message from the node.
+func newPoolError(msg string) *PoolError {
+	err := &PoolError{Message: msg}
+	switch {
+	case strings.Contains(msg, "overspend"):
+		err.Reason = ErrOverspend
+	case strings.Contains(msg, "logic eval error"):
+		logic := &LogicEvalError{Message: msg}
+		if i := strings.Index(msg, "logic eval error:"); i >= 0 {
+			logic.Message = strings.TrimSpace(msg[i+len("logic eval error:"):])
+		}
+		if m := pcExpr.FindStringSubmatch(msg); nil != m {
+			logic.Pc, _ = strconv.ParseUint(m[1], 10, 64)
+		}
+		err.Reason = logic
+	}
+	return err
+}
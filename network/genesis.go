@@ -0,0 +1,132 @@
+package net
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// GenesisAccount is an account funded at round 0 of a private network.
+type GenesisAccount struct {
+	Name    string
+	Address string
+	Balance uint64
+}
+
+// LoadGenesisAccounts resolves the configured genesis accounts to the
+// addresses stored in their keystore files, no passphrase is needed.
+func LoadGenesisAccounts() ([]GenesisAccount, error) {
+	list := []GenesisAccount{}
+	for _, a := range cfg.GenesisAccounts() {
+		addr, err := loadKeyStoreAddress(fmt.Sprintf(
+			"%s/accounts/%s.acc", cfg.AssetPath(), a.Name,
+		))
+		if err != nil {
+			return nil, fmt.Errorf("account %s: %s", a.Name, err)
+		}
+		list = append(list, GenesisAccount{
+			Name:    a.Name,
+			Address: addr,
+			Balance: a.Balance,
+		})
+	}
+	return list, nil
+}
+
+// addGenesisAccounts adds the accounts to the genesis of a private
+// network, this has to happen before the network is started. The root
+// genesis is rewritten and copied to all nodes, so they share one hash.
+func addGenesisAccounts(accounts []GenesisAccount) error {
+	if len(accounts) == 0 {
+		return nil
+	}
+	root := filepath.Join(cfg.DataPath(), "genesis.json")
+	if err := addGenesisAlloc(root, accounts); nil != err {
+		return fmt.Errorf("%s: %s", root, err)
+	}
+	data, err := os.ReadFile(root)
+	if err != nil {
+		return err
+	}
+
+	dirs, err := os.ReadDir(cfg.DataPath())
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		file := filepath.Join(cfg.DataPath(), dir.Name(), "genesis.json")
+		if _, err := os.Stat(file); !dir.IsDir() || nil != err {
+			continue
+		}
+		if err := os.WriteFile(file, data, 0644); nil != err {
+			return fmt.Errorf("%s: %s", file, err)
+		}
+	}
+	return nil
+}
+
+func addGenesisAlloc(file string, accounts []GenesisAccount) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	// Keep large amounts exact, they exceed the float precision
+	genesis := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&genesis); err != nil {
+		return err
+	}
+	alloc, ok := genesis["alloc"].([]interface{})
+	if !ok {
+		return fmt.Errorf("invalid genesis: missing alloc")
+	}
+
+	for _, a := range accounts {
+		for _, v := range alloc {
+			if entry, ok := v.(map[string]interface{}); ok && entry["addr"] == a.Address {
+				return fmt.Errorf("duplicate address: %s", a.Address)
+			}
+		}
+		alloc = append(alloc, map[string]interface{}{
+			"addr":    a.Address,
+			"comment": a.Name,
+			"state": map[string]interface{}{
+				"algo": a.Balance,
+			},
+		})
+	}
+	genesis["alloc"] = alloc
+
+	out, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, out, 0644)
+}
+
+func loadKeyStoreAddress(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	store := struct {
+		Addr string `json:"addr"`
+	}{}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return "", err
+	}
+	if len(store.Addr) == 0 {
+		return "", fmt.Errorf("keystore has no address, save it again")
+	}
+	if _, err := types.DecodeAddress(store.Addr); nil != err {
+		return "", fmt.Errorf("keystore address: %s", err)
+	}
+	return store.Addr, nil
+}
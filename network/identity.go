@@ -0,0 +1,182 @@
+package net
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// ErrGenesisMismatch is returned when the node is not on the expected network.
+var ErrGenesisMismatch = errors.New("genesis mismatch")
+
+// GenesisIdentity identifies a network, Hash is base64 encoded.
+type GenesisIdentity struct {
+	Id   string `json:"id"`
+	Hash string `json:"hash"`
+}
+
+var knownGenesis = map[cfg.Network]GenesisIdentity{
+	cfg.Testnet: {
+		Id:   "testnet-v1.0",
+		Hash: "SGO1GKSzyE7IEPItTxCByw9x8FmnrCDexi9/cOUJOiI=",
+	},
+	cfg.Mainnet: {
+		Id:   "mainnet-v1.0",
+		Hash: "wGHE2Pwdvd7S12BL5FaOP20EGYesN73ktiC1qzkkit8=",
+	},
+}
+
+// ExpectedGenesis returns the identity of the configured target. Config
+// values take precedence, then the known public networks, then the
+// genesis file of the node, its hash is computed from the file. Other
+// targets can not use the identity of a public network.
+func ExpectedGenesis() (GenesisIdentity, error) {
+	target := cfg.Target()
+	id, public := knownGenesis[target]
+
+	_, path := primaryNode()
+	if genesis, err := readGenesis(filepath.Join(path, "genesis.json")); nil == err {
+		fileId := fmt.Sprintf("%s-%s", genesis.Network, genesis.Id)
+		if public && id.Id != fileId {
+			return id, fmt.Errorf("genesis file: %w: expected %s, found %s", ErrGenesisMismatch, id.Id, fileId)
+		}
+		id.Id = fileId
+		if !public {
+			id.Hash = genesis.Hash()
+		}
+	} else if !os.IsNotExist(err) {
+		return id, fmt.Errorf("genesis file: %s", err)
+	}
+
+	if pin := cfg.Genesis(); len(pin.Id) > 0 {
+		id.Id = pin.Id
+	}
+	if pin := cfg.Genesis(); len(pin.Hash) > 0 {
+		id.Hash = pin.Hash
+	}
+	if len(id.Id) == 0 || len(id.Hash) == 0 {
+		return id, fmt.Errorf("genesis: unknown identity for the target")
+	}
+	if !public {
+		for _, known := range knownGenesis {
+			if id.Id == known.Id || id.Hash == known.Hash {
+				return id, fmt.Errorf("genesis: %w: %s target uses the %s network", ErrGenesisMismatch, cfg.TargetName(), known.Id)
+			}
+		}
+	}
+	return id, nil
+}
+
+// CheckGenesis verifies the params are for the expected network, call
+// it before signing anything with them.
+func CheckGenesis(params types.SuggestedParams) error {
+	id, err := ExpectedGenesis()
+	if err != nil {
+		return err
+	}
+	if params.GenesisID != id.Id {
+		return fmt.Errorf("genesis id: %w: expected %s, node reports %s", ErrGenesisMismatch, id.Id, params.GenesisID)
+	}
+	hash := base64.StdEncoding.EncodeToString(params.GenesisHash)
+	if hash != id.Hash {
+		return fmt.Errorf("genesis hash: %w: expected %s, node reports %s", ErrGenesisMismatch, id.Hash, hash)
+	}
+	return nil
+}
+
+// genesis is the genesis file of a node, the fields and codec tags
+// follow the node so the canonical encoding hashes the same.
+type genesis struct {
+	_struct     struct{}            `codec:",omitempty,omitemptyarray"`
+	Id          string              `codec:"id" json:"id"`
+	Network     string              `codec:"network" json:"network"`
+	Proto       string              `codec:"proto" json:"proto"`
+	Allocation  []genesisAllocation `codec:"alloc" json:"alloc"`
+	RewardsPool string              `codec:"rwd" json:"rwd"`
+	FeeSink     string              `codec:"fees" json:"fees"`
+	Timestamp   int64               `codec:"timestamp" json:"timestamp"`
+	Comment     string              `codec:"comment" json:"comment"`
+	DevMode     bool                `codec:"devmode" json:"devmode"`
+}
+
+type genesisAllocation struct {
+	Address string             `codec:"addr" json:"addr"`
+	Comment string             `codec:"comment" json:"comment"`
+	State   genesisAccountData `codec:"state" json:"state"`
+}
+
+type genesisAccountData struct {
+	_struct         struct{} `codec:",omitempty,omitemptyarray"`
+	Status          byte     `codec:"onl"`
+	MicroAlgos      uint64   `codec:"algo"`
+	VoteID          [32]byte `codec:"vote"`
+	SelectionID     [32]byte `codec:"sel"`
+	StateProofID    [64]byte `codec:"stprf"`
+	VoteFirstValid  uint64   `codec:"voteFst"`
+	VoteLastValid   uint64   `codec:"voteLst"`
+	VoteKeyDilution uint64   `codec:"voteKD"`
+}
+
+// UnmarshalJSON reads the base64 encoded keys of the genesis file.
+func (a *genesisAccountData) UnmarshalJSON(data []byte) error {
+	file := struct {
+		Status          byte   `json:"onl"`
+		MicroAlgos      uint64 `json:"algo"`
+		VoteID          []byte `json:"vote"`
+		SelectionID     []byte `json:"sel"`
+		StateProofID    []byte `json:"stprf"`
+		VoteFirstValid  uint64 `json:"voteFst"`
+		VoteLastValid   uint64 `json:"voteLst"`
+		VoteKeyDilution uint64 `json:"voteKD"`
+	}{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	for _, key := range []struct {
+		dst []byte
+		src []byte
+	}{
+		{a.VoteID[:], file.VoteID},
+		{a.SelectionID[:], file.SelectionID},
+		{a.StateProofID[:], file.StateProofID},
+	} {
+		if len(key.src) > 0 && len(key.src) != len(key.dst) {
+			return fmt.Errorf("invalid key length %d", len(key.src))
+		}
+		copy(key.dst, key.src)
+	}
+	a.Status = file.Status
+	a.MicroAlgos = file.MicroAlgos
+	a.VoteFirstValid = file.VoteFirstValid
+	a.VoteLastValid = file.VoteLastValid
+	a.VoteKeyDilution = file.VoteKeyDilution
+	return nil
+}
+
+// Hash is the base64 encoded genesis hash, the digest of the "GE"
+// prefixed canonical msgpack encoding.
+func (g genesis) Hash() string {
+	digest := sha512.Sum512_256(append([]byte("GE"), msgpack.Encode(&g)...))
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+func readGenesis(path string) (genesis, error) {
+	g := genesis{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return g, err
+	}
+	if err := json.Unmarshal(data, &g); err != nil {
+		return g, err
+	}
+	return g, nil
+}
@@ -0,0 +1,75 @@
+package net
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+)
+
+// testdata/genesis/<network>.json are the genesis files of the public
+// networks as installed with algod, installer/genesis in go-algorand.
+func TestGenesisHashPublic(t *testing.T) {
+	for _, known := range knownGenesis {
+		known := known
+		t.Run(known.Id, func(t *testing.T) {
+			path := filepath.Join("testdata", "genesis", known.Id+".json")
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				t.Skipf("fixture %s not found", path)
+			}
+			g, err := readGenesis(path)
+			if err != nil {
+				t.Fatalf("read genesis: %s", err)
+			}
+			if id := g.Network + "-" + g.Id; id != known.Id {
+				t.Fatalf("genesis id: expected %s, got %s", known.Id, id)
+			}
+			if hash := g.Hash(); hash != known.Hash {
+				t.Fatalf("genesis hash: expected %s, got %s", known.Hash, hash)
+			}
+		})
+	}
+}
+
+// The encoding has to match the node: keys sorted, empty genesis
+// fields omitted, the allocation fields always present.
+func TestGenesisEncoding(t *testing.T) {
+	g := genesis{}
+	err := json.Unmarshal([]byte(`{
+		"id": "v1",
+		"network": "devnet",
+		"proto": "future",
+		"alloc": [{
+			"addr": "A",
+			"comment": "",
+			"state": {"algo": 5, "onl": 1, "vote": "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="}
+		}],
+		"rwd": "R",
+		"fees": "F"
+	}`), &g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "86" + // map of 6
+		"a5616c6c6f63" + "91" + "83" + // alloc: [{3 fields}]
+		"a461646472" + "a141" + // addr: A
+		"a7636f6d6d656e74" + "a0" + // comment: ""
+		"a57374617465" + "83" + // state: {3 fields}
+		"a4616c676f" + "05" + // algo: 5
+		"a36f6e6c" + "01" + // onl: 1
+		"a4766f7465" + "c420" + "0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20" +
+		"a466656573" + "a146" + // fees: F
+		"a26964" + "a27631" + // id: v1
+		"a76e6574776f726b" + "a66465766e6574" + // network: devnet
+		"a570726f746f" + "a6667574757265" + // proto: future
+		"a3727764" + "a152" // rwd: R
+	data, _ := hex.DecodeString(expected)
+	if enc := msgpack.Encode(&g); !bytes.Equal(enc, data) {
+		t.Fatalf("encoding:\nexpected %x\ngot      %x", data, enc)
+	}
+}
@@ -0,0 +1,165 @@
+package net
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+)
+
+// LockMode is the mode of a data path lock.
+type LockMode int
+
+const (
+	// LockShared allows other shared holders, for readers.
+	LockShared LockMode = iota
+	// LockExclusive allows no other holders, for create and destroy.
+	LockExclusive
+)
+
+// ErrLocked is returned when the lock is not acquired within the timeout.
+var ErrLocked = errors.New("data path is locked")
+
+// Lock is an advisory lock on the network data path shared between
+// processes. The lock file lives next to the data path so it can be
+// held while the path is created or removed. The kernel drops the lock
+// of a crashed process, its left over pid entry is pruned as stale.
+type Lock struct {
+	file *os.File
+	path string
+}
+
+func lockPath() string {
+	return fmt.Sprintf("%s.lock", strings.TrimRight(cfg.DataPath(), "/"))
+}
+
+// AcquireLock waits up to the configured timeout for the lock.
+func AcquireLock(mode LockMode) (*Lock, error) {
+	path := lockPath()
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("lock: %s", err)
+	}
+
+	how := syscall.LOCK_SH
+	if LockExclusive == mode {
+		how = syscall.LOCK_EX
+	}
+	deadline := time.Now().Add(time.Duration(cfg.Timeout()) * time.Second)
+	for {
+		err = syscall.Flock(int(file.Fd()), how|syscall.LOCK_NB)
+		if nil == err {
+			break
+		}
+		if err != syscall.EWOULDBLOCK || time.Now().After(deadline) {
+			file.Close()
+			if err != syscall.EWOULDBLOCK {
+				return nil, fmt.Errorf("lock: %s", err)
+			}
+			return nil, lockedError(path)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	lock := &Lock{file: file, path: path}
+	if err := updateLockHolders(path, func(pids []int) []int {
+		return append(pids, os.Getpid())
+	}); nil != err {
+		lock.Release()
+		return nil, fmt.Errorf("lock: %s", err)
+	}
+	return lock, nil
+}
+
+// Release drops the lock, it is safe to call more than once.
+func (l *Lock) Release() error {
+	if nil == l || nil == l.file {
+		return nil
+	}
+	updateLockHolders(l.path, func(pids []int) []int {
+		for i, pid := range pids {
+			if pid == os.Getpid() {
+				return append(pids[:i], pids[i+1:]...)
+			}
+		}
+		return pids
+	})
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	l.file.Close()
+	l.file = nil
+	return err
+}
+
+// WithLock runs fn while holding the data path lock. Work that needs
+// the network to stay up holds LockShared, the lifecycle functions take
+// LockExclusive and wait for it.
+func WithLock(mode LockMode, fn func() error) error {
+	lock, err := AcquireLock(mode)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	return fn()
+}
+
+func lockedError(path string) error {
+	pids := []string{}
+	for _, pid := range readLockHolders(path) {
+		pids = append(pids, strconv.Itoa(pid))
+	}
+	if len(pids) == 0 {
+		return fmt.Errorf("lock: %w", ErrLocked)
+	}
+	return fmt.Errorf("lock: %w by pid %s", ErrLocked, strings.Join(pids, ", "))
+}
+
+// updateLockHolders changes the pid list under its own short lock,
+// entries of processes that no longer exist are dropped.
+func updateLockHolders(path string, update func([]int) []int) error {
+	file, err := os.OpenFile(path+".pid", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); nil != err {
+		return err
+	}
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	pids := update(readLockHolders(path))
+	lines := []string{}
+	for _, pid := range pids {
+		lines = append(lines, strconv.Itoa(pid))
+	}
+	if err := file.Truncate(0); nil != err {
+		return err
+	}
+	_, err = file.WriteAt([]byte(strings.Join(lines, "\n")), 0)
+	return err
+}
+
+func readLockHolders(path string) []int {
+	data, err := os.ReadFile(path + ".pid")
+	if err != nil {
+		return nil
+	}
+	pids := []int{}
+	for _, line := range strings.Fields(string(data)) {
+		pid, err := strconv.Atoi(line)
+		if err != nil || !isProcessAlive(pid) {
+			continue
+		}
+		pids = append(pids, pid)
+	}
+	return pids
+}
+
+func isProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return nil == err || err == syscall.EPERM
+}
@@ -0,0 +1,271 @@
+package net
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+)
+
+// LogFiles are the node log files read by Logs.
+var LogFiles = []string{"node.log", "algod-err.log"}
+
+// LogOptions selects the log lines of interest. Node limits the output to
+// a single node directory, Levels and Contains match any of the values and
+// lines without a timestamp are dropped when a time window is set. Follow
+// keeps reading new lines until ctx is done.
+type LogOptions struct {
+	Node     string
+	Levels   []string
+	Contains []string
+	Since    time.Time
+	Until    time.Time
+	Follow   bool
+}
+
+// LogEntry is a single log line, the json fields of node.log are parsed
+// into Time, Level and Message, all other lines have the level "error"
+// for algod-err.log and "info" otherwise.
+type LogEntry struct {
+	Node    string
+	File    string
+	Time    time.Time
+	Level   string
+	Message string
+	Fields  map[string]interface{}
+	Raw     string
+}
+
+func (e LogEntry) String() string {
+	return fmt.Sprintf("%s %s [%s] %s: %s",
+		e.Time.Format(time.RFC3339), e.Node, e.Level, e.File, e.Message,
+	)
+}
+
+// Logs streams the matching log lines of the network nodes. Both channels
+// are closed when all files are read or, with Follow, when ctx is done.
+func Logs(ctx context.Context, opts LogOptions) (<-chan LogEntry, <-chan error) {
+	entries := make(chan LogEntry)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(entries)
+		defer close(errs)
+
+		files, err := logFiles(opts.Node)
+		if err != nil {
+			errs <- fmt.Errorf("logs: %s", err)
+			return
+		}
+
+		done := make(chan error, len(files))
+		for _, f := range files {
+			go func(f logFile) {
+				done <- readLog(ctx, f, opts, entries)
+			}(f)
+		}
+		for range files {
+			if err := <-done; nil != err && nil == ctx.Err() {
+				select {
+				case errs <- fmt.Errorf("logs: %s", err):
+				default:
+				}
+			}
+		}
+	}()
+
+	return entries, errs
+}
+
+// LogExcerpt returns up to max matching lines, the most recent ones, as
+// text to attach to an error. Follow is ignored.
+func LogExcerpt(ctx context.Context, opts LogOptions, max int) (string, error) {
+	opts.Follow = false
+	entries, errs := Logs(ctx, opts)
+
+	list := []LogEntry{}
+	for entry := range entries {
+		list = append(list, entry)
+	}
+	if err := <-errs; nil != err {
+		return "", err
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Time.Before(list[j].Time)
+	})
+	if max > 0 && len(list) > max {
+		list = list[len(list)-max:]
+	}
+	lines := make([]string, len(list))
+	for i, entry := range list {
+		lines[i] = entry.String()
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+type logFile struct {
+	node string
+	path string
+}
+
+func logFiles(node string) ([]logFile, error) {
+	dirs := map[string]string{}
+	if cfg.Testnet == cfg.Target() || cfg.Mainnet == cfg.Target() {
+		dirs[filepath.Base(cfg.DataPath())] = cfg.DataPath()
+	} else {
+		list, err := os.ReadDir(cfg.DataPath())
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range list {
+			if entry.IsDir() {
+				dirs[entry.Name()] = filepath.Join(cfg.DataPath(), entry.Name())
+			}
+		}
+	}
+	if len(node) > 0 {
+		path, ok := dirs[node]
+		if !ok {
+			return nil, fmt.Errorf("unknown node: %s", node)
+		}
+		dirs = map[string]string{node: path}
+	}
+
+	files := []logFile{}
+	for name, path := range dirs {
+		for _, file := range LogFiles {
+			file = filepath.Join(path, file)
+			if _, err := os.Stat(file); nil == err {
+				files = append(files, logFile{node: name, path: file})
+			}
+		}
+	}
+	return files, nil
+}
+
+func readLog(ctx context.Context, f logFile, opts LogOptions, entries chan<- LogEntry) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	partial := ""
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			if !opts.Follow {
+				line = partial + line
+				if len(strings.TrimSpace(line)) > 0 {
+					return sendLog(ctx, parseLog(f, line), opts, entries)
+				}
+				return nil
+			}
+			// Keep the partial line until it is completed
+			partial += line
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(250 * time.Millisecond):
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		line, partial = partial+line, ""
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		if err := sendLog(ctx, parseLog(f, line), opts, entries); nil != err {
+			return err
+		}
+	}
+}
+
+func sendLog(ctx context.Context, entry LogEntry, opts LogOptions, entries chan<- LogEntry) error {
+	if !opts.match(entry) {
+		return nil
+	}
+	select {
+	case entries <- entry:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func parseLog(f logFile, line string) LogEntry {
+	line = strings.TrimRight(line, "\r\n")
+	entry := LogEntry{
+		Node:    f.node,
+		File:    filepath.Base(f.path),
+		Level:   "info",
+		Message: line,
+		Raw:     line,
+	}
+	if entry.File == "algod-err.log" {
+		entry.Level = "error"
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return entry
+	}
+	entry.Fields = fields
+	if v, ok := fields["level"].(string); ok {
+		entry.Level = v
+	}
+	if v, ok := fields["msg"].(string); ok {
+		entry.Message = v
+	}
+	if v, ok := fields["time"].(string); ok {
+		entry.Time, _ = time.Parse(time.RFC3339Nano, v)
+	}
+	return entry
+}
+
+func (o LogOptions) match(entry LogEntry) bool {
+	if len(o.Levels) > 0 {
+		found := false
+		for _, level := range o.Levels {
+			if strings.EqualFold(level, entry.Level) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !o.Since.IsZero() || !o.Until.IsZero() {
+		if entry.Time.IsZero() {
+			return false
+		}
+		if !o.Since.IsZero() && entry.Time.Before(o.Since) {
+			return false
+		}
+		if !o.Until.IsZero() && entry.Time.After(o.Until) {
+			return false
+		}
+	}
+	if len(o.Contains) > 0 {
+		for _, s := range o.Contains {
+			if strings.Contains(entry.Raw, s) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
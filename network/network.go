@@ -1,13 +1,14 @@
 package net
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -17,10 +18,12 @@ import (
 func Start() error {
 	fmt.Println(":: Start network:", cfg.DataPath())
 
-	if cfg.Testnet == cfg.Target() || cfg.Mainnet == cfg.Target() {
-		return startNetworkPub()
-	}
-	return startNetworkPriv()
+	return WithLock(LockExclusive, func() error {
+		if cfg.Testnet == cfg.Target() || cfg.Mainnet == cfg.Target() {
+			return startNetworkPub()
+		}
+		return startNetworkPriv()
+	})
 }
 
 func Stop() error {
@@ -33,16 +36,17 @@ func Stop() error {
 		cmd = fmt.Sprintf("goal network stop -r %s", cfg.DataPath())
 	}
 
-	fmt.Println(">>", cmd)
-	out, err := exec.Command("bash", "-c", cmd).Output()
-	if len(out) > 0 {
-		fmt.Println(string(out))
-	}
-	if nil != err {
-		return err
-	}
-
-	return nil
+	return WithLock(LockExclusive, func() error {
+		fmt.Println(">>", cmd)
+		out, err := exec.Command("bash", "-c", cmd).Output()
+		if len(out) > 0 {
+			fmt.Println(string(out))
+		}
+		if nil != err {
+			return err
+		}
+		return nil
+	})
 }
 
 func Status() error {
@@ -55,39 +59,62 @@ func Status() error {
 		cmd = fmt.Sprintf("goal network status -r %s", cfg.DataPath())
 	}
 
-	fmt.Println(">>", cmd)
-	out, err := exec.Command("bash", "-c", cmd).Output()
-	if len(out) > 0 {
-		fmt.Println(string(out))
-	}
-	if nil != err {
-		return err
-	}
-
-	return nil
+	return WithLock(LockShared, func() error {
+		fmt.Println(">>", cmd)
+		out, err := exec.Command("bash", "-c", cmd).Output()
+		if len(out) > 0 {
+			fmt.Println(string(out))
+		}
+		if nil != err {
+			return err
+		}
+		return nil
+	})
 }
 
 func Create() error {
-	if _, err := os.Stat(cfg.DataPath()); nil == err {
-		return fmt.Errorf("create network: path already exists")
-	}
-	fmt.Println(":: Create network:", cfg.DataPath())
-
-	if cfg.Testnet == cfg.Target() {
-		return createNetworkPub("genesisfiles/testnet/genesis.json")
-	} else if cfg.Mainnet == cfg.Target() {
-		return createNetworkPub("genesisfiles/mainnet/genesis.json")
-	}
-	return createNetworkPriv()
+	return WithLock(LockExclusive, func() error {
+		if _, err := os.Stat(cfg.DataPath()); nil == err {
+			return fmt.Errorf("create network: path already exists")
+		}
+		fmt.Println(":: Create network:", cfg.DataPath())
+
+		if cfg.Testnet == cfg.Target() {
+			return createNetworkPub("genesisfiles/testnet/genesis.json")
+		} else if cfg.Mainnet == cfg.Target() {
+			return createNetworkPub("genesisfiles/mainnet/genesis.json")
+		}
+		return createNetworkPriv()
+	})
 }
 
-func Destroy() error {
+func Destroy(opts DestroyOptions) error {
 	fmt.Println(":: Destroy network:", cfg.DataPath())
 
-	if cfg.Testnet == cfg.Target() || cfg.Mainnet == cfg.Target() {
-		return destroyNetworkPub()
-	}
-	return destroyNetworkPriv()
+	public := cfg.Testnet == cfg.Target() || cfg.Mainnet == cfg.Target()
+	if public && !opts.Force && !opts.DryRun {
+		return fmt.Errorf("destroy network: refusing to remove public network data without force")
+	}
+
+	return WithLock(LockExclusive, func() error {
+		err := IsDataPath()
+		if errors.Is(err, ErrNoMarker) {
+			if !opts.Force {
+				return fmt.Errorf("destroy network: %s, use force to remove it", err)
+			}
+			err = isLegacyDataPath()
+		}
+		if nil != err {
+			return fmt.Errorf("destroy network: %s", err)
+		}
+		if opts.DryRun {
+			return printDataPath()
+		}
+		if public {
+			return destroyNetworkPub()
+		}
+		return destroyNetworkPriv()
+	})
 }
 
 func IsActive() bool {
@@ -181,7 +208,15 @@ func createNetworkPub(srcPath string) error {
 	); nil != err {
 		return fmt.Errorf("create network: failed write config: %s", err)
 	}
-	return nil
+
+	ports, err := assignPorts(map[string]string{
+		filepath.Base(cfg.DataPath()): cfg.DataPath(),
+	})
+	if nil != err {
+		return fmt.Errorf("create network: assign ports: %s", err)
+	}
+	printPorts(ports)
+	return writeMarker()
 }
 
 func createNetworkPriv() error {
@@ -189,6 +224,10 @@ func createNetworkPriv() error {
 	if _, err := loadPrivateNetworkConfig(cfgFile); nil != err {
 		return fmt.Errorf("create networ: load config: %s", err)
 	}
+	accounts, err := LoadGenesisAccounts()
+	if nil != err {
+		return fmt.Errorf("create network: genesis accounts: %s", err)
+	}
 	cmd := fmt.Sprintf(
 		"goal network create -n devnet -t %s -r %s",
 		cfgFile, cfg.DataPath(),
@@ -202,27 +241,61 @@ func createNetworkPriv() error {
 		return err
 	}
 
-	node := cfg.NodeConfig{}
+	// Fund the accounts at round 0, before the first start
+	for _, a := range accounts {
+		fmt.Printf(">> genesis account %s: %s (%d)\n", a.Name, a.Address, a.Balance)
+	}
+	if err := addGenesisAccounts(accounts); nil != err {
+		return fmt.Errorf("create network: genesis accounts: %s", err)
+	}
+
 	// Enable the developers api to compile teal code
 	// ToDo fix the hard coded node path below, none default
 	cfgFile = fmt.Sprintf("%s/primary/config.json", cfg.DataPath())
-	file, err := os.ReadFile(cfgFile)
-	if err := json.Unmarshal(file, &node); nil != err {
+	if err := updateJsonFile(cfgFile, func(m map[string]interface{}) {
+		m["EnableDeveloperAPI"] = true
+	}); nil != err {
 		return err
 	}
-	node.EnableDeveloperAPI = true
-	jsonString, _ := json.Marshal(node)
-	if os.WriteFile(cfgFile, jsonString, os.ModePerm); nil != err {
+
+	nodes := map[string]string{}
+	dirs, err := os.ReadDir(cfg.DataPath())
+	if nil != err {
 		return err
 	}
+	for _, dir := range dirs {
+		path := filepath.Join(cfg.DataPath(), dir.Name())
+		if _, err := os.Stat(filepath.Join(path, "config.json")); dir.IsDir() && nil == err {
+			nodes[dir.Name()] = path
+		}
+	}
+	ports, err := assignPorts(nodes)
+	if nil != err {
+		return fmt.Errorf("create network: assign ports: %s", err)
+	}
+	printPorts(ports)
+	return writeMarker()
+}
 
-	return nil
+func printPorts(ports map[string]NodePorts) {
+	for name, p := range ports {
+		fmt.Printf(">> node %s: algod %d, kmd %d, gossip %d\n", name, p.Algod, p.Kmd, p.Gossip)
+	}
 }
 
 func destroyNetworkPub() error {
-	fmt.Println(">>", fmt.Sprintf("goal network delete -r %s", cfg.DataPath()))
-	cmd := fmt.Sprintf("goal network stop -r %s", cfg.DataPath())
-	exec.Command("bash", "-c", cmd).Output()
+	if IsActive() {
+		cmd := fmt.Sprintf("goal node stop -d %s", cfg.DataPath())
+		fmt.Println(">>", cmd)
+		out, err := exec.Command("bash", "-c", cmd).Output()
+		if len(out) > 0 {
+			fmt.Println(string(out))
+		}
+		if nil != err {
+			return fmt.Errorf("destroy network: stop node: %s", err)
+		}
+	}
+	fmt.Println(">> remove", cfg.DataPath())
 	return os.RemoveAll(cfg.DataPath())
 }
 
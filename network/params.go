@@ -0,0 +1,90 @@
+package net
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// ParamsOptions adjusts the suggested params of the node, zero values
+// keep the suggestion. Fee is a flat fee with FlatFee set, and a fee per
+// byte otherwise. MinFee is the fee floor, InnerTxns adds a minimum fee
+// for each inner transaction and forces a flat fee. ValidRounds sets the
+// validity window and FirstValidOffset moves its start into the future.
+type ParamsOptions struct {
+	Fee     uint64
+	FlatFee bool
+	MinFee  uint64
+
+	InnerTxns uint64
+
+	ValidRounds      uint64
+	FirstValidOffset uint64
+}
+
+func MakeTxnParamsWith(opts ParamsOptions) (types.SuggestedParams, error) {
+	cln, err := MakeClient()
+	if err != nil {
+		return types.SuggestedParams{}, fmt.Errorf("make client: %s", err)
+	}
+	return MakeTxnParamsWithClient(cln, opts)
+}
+
+func MakeTxnParamsWithClient(cln *algod.Client, opts ParamsOptions) (types.SuggestedParams, error) {
+	txnParams, err := cln.SuggestedParams().Do(context.Background())
+	if err != nil {
+		return types.SuggestedParams{}, fmt.Errorf("suggested params: %s", err)
+	}
+	if err := CheckGenesis(txnParams); nil != err {
+		return types.SuggestedParams{}, fmt.Errorf("suggested params: %w", err)
+	}
+	return opts.Apply(txnParams), nil
+}
+
+// Apply returns a copy of params with the options applied.
+func (o ParamsOptions) Apply(params types.SuggestedParams) types.SuggestedParams {
+	if o.MinFee > params.MinFee {
+		params.MinFee = o.MinFee
+	}
+
+	if o.Fee > 0 {
+		params.Fee = types.MicroAlgos(o.Fee)
+		params.FlatFee = o.FlatFee
+	} else if o.FlatFee {
+		params.Fee = types.MicroAlgos(params.MinFee)
+		params.FlatFee = true
+	}
+	if o.InnerTxns > 0 {
+		// Inner transactions are paid for by the outer one
+		fee := params.MinFee
+		if params.FlatFee && uint64(params.Fee) > fee {
+			fee = uint64(params.Fee)
+		}
+		params.Fee = types.MicroAlgos(fee * (1 + o.InnerTxns))
+		params.FlatFee = true
+	}
+	if params.FlatFee && uint64(params.Fee) < params.MinFee {
+		params.Fee = types.MicroAlgos(params.MinFee)
+	}
+
+	if o.FirstValidOffset > 0 || o.ValidRounds > 0 {
+		window := params.LastRoundValid - params.FirstRoundValid
+		if o.ValidRounds > 0 {
+			window = types.Round(o.ValidRounds)
+		}
+		params.FirstRoundValid += types.Round(o.FirstValidOffset)
+		params.LastRoundValid = params.FirstRoundValid + window
+	}
+	return params
+}
+
+// ApplyMinFee raises the fee of a transaction build with a fee per byte
+// to the fee floor of params, the SDK only enforces the protocol minimum.
+func ApplyMinFee(tx types.Transaction, params types.SuggestedParams) types.Transaction {
+	if uint64(tx.Fee) < params.MinFee {
+		tx.Fee = types.MicroAlgos(params.MinFee)
+	}
+	return tx
+}
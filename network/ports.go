@@ -0,0 +1,176 @@
+package net
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+)
+
+// PortsFile records the ports of all nodes in the network data path.
+const PortsFile = "ports.json"
+
+// NodePorts holds the ports assigned to a node, Gossip is only set
+// for relay nodes of a private network.
+type NodePorts struct {
+	Algod  uint16 `json:"algod"`
+	Kmd    uint16 `json:"kmd"`
+	Gossip uint16 `json:"gossip,omitempty"`
+}
+
+// LoadPorts returns the recorded ports by node name.
+func LoadPorts() (map[string]NodePorts, error) {
+	data, err := os.ReadFile(filepath.Join(cfg.DataPath(), PortsFile))
+	if err != nil {
+		return nil, err
+	}
+	ports := map[string]NodePorts{}
+	if err := json.Unmarshal(data, &ports); err != nil {
+		return nil, err
+	}
+	return ports, nil
+}
+
+// assignPorts sets the algod, kmd and gossip ports of the nodes in the
+// data path and records them. The configured ports are used for the
+// first node, relays first, all other ports are picked free.
+func assignPorts(nodes map[string]string) (map[string]NodePorts, error) {
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := isRelayNode(nodes[names[i]]), isRelayNode(nodes[names[j]])
+		if ri != rj {
+			return ri
+		}
+		return names[i] < names[j]
+	})
+
+	used := map[uint16]bool{}
+	ports := map[string]NodePorts{}
+	for i, name := range names {
+		want := cfg.PortSetup{}
+		if i == 0 {
+			want = cfg.Ports()
+		}
+
+		p := NodePorts{}
+		var err error
+		if p.Algod, err = pickPort(want.Algod, used); err != nil {
+			return nil, fmt.Errorf("algod port: %s", err)
+		}
+		if p.Kmd, err = pickPort(want.Kmd, used); err != nil {
+			return nil, fmt.Errorf("kmd port: %s", err)
+		}
+		if isRelayNode(nodes[name]) {
+			if p.Gossip, err = pickPort(want.Gossip, used); err != nil {
+				return nil, fmt.Errorf("gossip port: %s", err)
+			}
+		}
+		if err := applyPorts(nodes[name], p); nil != err {
+			return nil, fmt.Errorf("node %s: %s", name, err)
+		}
+		ports[name] = p
+	}
+
+	out, err := json.MarshalIndent(ports, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(cfg.DataPath(), PortsFile), out, 0644); nil != err {
+		return nil, err
+	}
+	return ports, nil
+}
+
+func applyPorts(dir string, p NodePorts) error {
+	err := updateJsonFile(filepath.Join(dir, "config.json"), func(m map[string]interface{}) {
+		m["EndpointAddress"] = fmt.Sprintf("127.0.0.1:%d", p.Algod)
+		if p.Gossip > 0 {
+			m["NetAddress"] = fmt.Sprintf("127.0.0.1:%d", p.Gossip)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	kmdDir, err := kmdPath(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(kmdDir, 0700); nil != err {
+		return err
+	}
+	return updateJsonFile(filepath.Join(kmdDir, "kmd_config.json"), func(m map[string]interface{}) {
+		m["address"] = fmt.Sprintf("127.0.0.1:%d", p.Kmd)
+	})
+}
+
+// kmdPath returns the kmd directory of a node, the default when missing.
+func kmdPath(dir string) (string, error) {
+	list, err := filepath.Glob(filepath.Join(dir, "kmd-v*"))
+	if err != nil {
+		return "", err
+	}
+	if len(list) > 0 {
+		sort.Strings(list)
+		return list[len(list)-1], nil
+	}
+	return filepath.Join(dir, "kmd-v0.5"), nil
+}
+
+func isRelayNode(dir string) bool {
+	node := cfg.NodeConfig{}
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(data, &node); err != nil {
+		return false
+	}
+	return len(node.NetAddress) > 0
+}
+
+// pickPort checks a requested port or picks a free one.
+func pickPort(port uint16, used map[uint16]bool) (uint16, error) {
+	for {
+		l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			return 0, err
+		}
+		p := uint16(l.Addr().(*net.TCPAddr).Port)
+		l.Close()
+		if used[p] && port > 0 {
+			return 0, fmt.Errorf("port %d assigned twice", port)
+		}
+		if !used[p] {
+			used[p] = true
+			return p, nil
+		}
+	}
+}
+
+// updateJsonFile changes a json object file and keeps unknown fields,
+// a missing file is created.
+func updateJsonFile(file string, update func(map[string]interface{})) error {
+	m := map[string]interface{}{}
+	data, err := os.ReadFile(file)
+	if nil == err {
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("%s: %s", file, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	update(m)
+	out, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, out, 0644)
+}
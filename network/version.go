@@ -0,0 +1,328 @@
+package net
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// ErrUnsupported is returned when the node lacks a required feature.
+var ErrUnsupported = errors.New("not supported by node")
+
+// NodeVersion is a semantic algod version.
+type NodeVersion struct {
+	Major uint64
+	Minor uint64
+	Build uint64
+}
+
+func (v NodeVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Build)
+}
+
+// Less reports whether v is older than o.
+func (v NodeVersion) Less(o NodeVersion) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Build < o.Build
+}
+
+// tealVersions lists the first algod release for each TEAL version,
+// it ends with the newest version known to this library. Newer nodes
+// may support more versions, the table does not limit them.
+var tealVersions = []struct {
+	teal    uint64
+	release NodeVersion
+}{
+	{1, NodeVersion{2, 0, 0}},
+	{2, NodeVersion{2, 1, 0}},
+	{3, NodeVersion{2, 5, 0}},
+	{4, NodeVersion{2, 7, 1}},
+	{5, NodeVersion{3, 2, 0}},
+	{6, NodeVersion{3, 5, 0}},
+}
+
+// extraPagesRelease is the first algod release with extra program pages.
+var extraPagesRelease = NodeVersion{2, 7, 1}
+
+// tealProbeLimit bounds the TEAL versions probed on the consensus.
+const tealProbeLimit = 64
+
+// NodeCapabilities describes the node at the configured data path.
+// Version is reported by the running node and Binary by the algod
+// binary in the node path. Consensus is the current protocol, it can
+// lag behind the TEAL versions the binary supports on public networks.
+//
+// ConsensusTealVersion is the newest TEAL version the consensus accepts,
+// probed with dryruns when the developer api is enabled, and zero when
+// it is unknown. NodeTealVersion is the newest version of the release
+// table, zero when the node is newer than the table. MaxTealVersion is
+// the consensus version when known, else the node version, a zero
+// limit is not checked.
+type NodeCapabilities struct {
+	Version NodeVersion
+	Binary  NodeVersion
+
+	Consensus            string
+	NextConsensus        string
+	SupportedNext        bool
+	ApiVersions          []string
+	ConsensusTealVersion uint64
+	NodeTealVersion      uint64
+	MaxTealVersion       uint64
+	ExtraPages           bool
+	DeveloperAPI         bool
+	DevMode              bool
+	DryrunAvailable      bool
+}
+
+// capsKey identifies a node process and the network it runs, a restart
+// or a new network invalidates the cached capabilities.
+type capsKey struct {
+	path    string
+	pid     string
+	genesis string
+	version NodeVersion
+}
+
+var capsCache = struct {
+	sync.Mutex
+	entries map[capsKey]NodeCapabilities
+}{entries: map[capsKey]NodeCapabilities{}}
+
+// Capabilities queries the running node and its binaries. The result
+// is cached per node process and genesis. Failed probes fall back to
+// the release table, those results are not cached.
+func Capabilities() (NodeCapabilities, error) {
+	c := NodeCapabilities{}
+
+	cln, err := MakeClient()
+	if err != nil {
+		return c, fmt.Errorf("capabilities: make client: %s", err)
+	}
+	ver, err := cln.Versions().Do(context.Background())
+	if err != nil {
+		return c, fmt.Errorf("capabilities: versions: %s", err)
+	}
+	c.ApiVersions = ver.Versions
+	c.Version = NodeVersion{ver.Build.Major, ver.Build.Minor, ver.Build.BuildNumber}
+
+	_, path := primaryNode()
+	pid, _ := getFirstLineFromFile(filepath.Join(path, "algod.pid"))
+	key := capsKey{path, pid, fmt.Sprintf("%s/%x", ver.GenesisID, ver.GenesisHash), c.Version}
+	capsCache.Lock()
+	cached, ok := capsCache.entries[key]
+	capsCache.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	complete := true
+	bin, err := binaryVersion(filepath.Join(cfg.NodePath(), "algod"))
+	if err != nil {
+		fmt.Println(">> capabilities: algod binary:", err)
+		complete = false
+	}
+	c.Binary = bin
+
+	status, err := cln.Status().Do(context.Background())
+	if err != nil {
+		fmt.Println(">> capabilities: status:", err)
+		complete = false
+	}
+	c.Consensus = status.LastVersion
+	c.NextConsensus = status.NextVersion
+	c.SupportedNext = status.NextVersionSupported
+
+	for _, t := range tealVersions {
+		if !c.Version.Less(t.release) {
+			c.NodeTealVersion = t.teal
+		}
+	}
+	if newest := tealVersions[len(tealVersions)-1]; newest.release.Less(c.Version) {
+		c.NodeTealVersion = 0
+	}
+	c.ExtraPages = !c.Version.Less(extraPagesRelease)
+
+	// The developer api is probed, the config may be changed on disk
+	if _, err := cln.TealCompile([]byte("int 1")).Do(context.Background()); nil == err {
+		c.DeveloperAPI = true
+	}
+	c.DryrunAvailable = c.DeveloperAPI
+
+	if c.DryrunAvailable && len(c.Consensus) > 0 {
+		teal, err := consensusTealVersion(cln, c.Consensus, c.NodeTealVersion)
+		if err != nil {
+			fmt.Println(">> capabilities: consensus teal version:", err)
+			complete = false
+		}
+		c.ConsensusTealVersion = teal
+	}
+	c.MaxTealVersion = c.NodeTealVersion
+	if c.ConsensusTealVersion > 0 {
+		c.MaxTealVersion = c.ConsensusTealVersion
+	}
+
+	if data, err := os.ReadFile(filepath.Join(path, "genesis.json")); nil == err {
+		genesis := struct {
+			DevMode bool `json:"devmode"`
+		}{}
+		if err := json.Unmarshal(data, &genesis); nil == err {
+			c.DevMode = genesis.DevMode
+		}
+	}
+
+	if complete {
+		capsCache.Lock()
+		capsCache.entries[key] = c
+		capsCache.Unlock()
+	}
+	return c, nil
+}
+
+func (c NodeCapabilities) RequireTealVersion(version uint64) error {
+	switch {
+	case c.ConsensusTealVersion > 0 && version > c.ConsensusTealVersion:
+		return fmt.Errorf("teal version %d: %w, consensus %s supports up to version %d",
+			version, ErrUnsupported, c.Consensus, c.ConsensusTealVersion,
+		)
+	case c.MaxTealVersion > 0 && version > c.MaxTealVersion:
+		return fmt.Errorf("teal version %d: %w, algod %s supports up to version %d",
+			version, ErrUnsupported, c.Version, c.MaxTealVersion,
+		)
+	}
+	return nil
+}
+
+func (c NodeCapabilities) RequireExtraPages(pages uint32) error {
+	if pages > 0 && !c.ExtraPages {
+		return fmt.Errorf("extra program pages: %w, algod %s requires %s or newer",
+			ErrUnsupported, c.Version, extraPagesRelease,
+		)
+	}
+	return nil
+}
+
+func (c NodeCapabilities) RequireDeveloperAPI() error {
+	if !c.DeveloperAPI {
+		return fmt.Errorf("developer api: %w, set EnableDeveloperAPI in the node config", ErrUnsupported)
+	}
+	return nil
+}
+
+func (c NodeCapabilities) RequireDryrun() error {
+	if !c.DryrunAvailable {
+		return fmt.Errorf("dryrun: %w, set EnableDeveloperAPI in the node config", ErrUnsupported)
+	}
+	return nil
+}
+
+func (c NodeCapabilities) RequireDevMode() error {
+	if !c.DevMode {
+		return fmt.Errorf("dev mode: %w, the network genesis has no devmode", ErrUnsupported)
+	}
+	return nil
+}
+
+// RequireProgram checks the version of a compiled program.
+func (c NodeCapabilities) RequireProgram(prog []byte) error {
+	version, n := binary.Uvarint(prog)
+	if n <= 0 {
+		return fmt.Errorf("invalid program: missing version")
+	}
+	return c.RequireTealVersion(version)
+}
+
+// consensusTealVersion finds the newest TEAL version the consensus
+// accepts, it dryruns a logic sig per version starting at from.
+func consensusTealVersion(cln *algod.Client, proto string, from uint64) (uint64, error) {
+	accepts := func(version uint64) (bool, error) {
+		// intcblock 1, intc_0: valid in every version
+		prog := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+4)
+		prog = append(prog[:binary.PutUvarint(prog, version)], 0x20, 0x01, 0x01, 0x22)
+
+		req := models.DryrunRequest{
+			ProtocolVersion: proto,
+			Txns: []types.SignedTxn{{
+				Lsig: types.LogicSig{Logic: prog},
+				Txn:  types.Transaction{Type: types.PaymentTx},
+			}},
+		}
+		res, err := cln.TealDryrun(req).Do(context.Background())
+		if err != nil {
+			return false, err
+		}
+		if len(res.Txns) == 0 || len(res.Txns[0].LogicSigMessages) == 0 {
+			return false, fmt.Errorf("dryrun: no logic sig result")
+		}
+		return res.Txns[0].LogicSigMessages[0] == "PASS", nil
+	}
+
+	version := from
+	if version == 0 {
+		version = tealVersions[len(tealVersions)-1].teal
+	}
+	ok, err := accepts(version)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		for version < tealProbeLimit {
+			if ok, err = accepts(version + 1); nil != err {
+				return 0, err
+			}
+			if !ok {
+				break
+			}
+			version += 1
+		}
+		return version, nil
+	}
+	for version > 1 {
+		version -= 1
+		if ok, err = accepts(version); nil != err {
+			return 0, err
+		}
+		if ok {
+			return version, nil
+		}
+	}
+	return 0, fmt.Errorf("no version accepted by %s", proto)
+}
+
+var versionExpr = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
+
+// binaryVersion runs `<bin> -v`, the version is on the second line.
+func binaryVersion(bin string) (NodeVersion, error) {
+	out, err := exec.Command(bin, "-v").Output()
+	if err != nil {
+		return NodeVersion{}, err
+	}
+	m := versionExpr.FindStringSubmatch(string(out))
+	if nil == m {
+		return NodeVersion{}, fmt.Errorf("unknown version output: %s", out)
+	}
+	v := NodeVersion{}
+	v.Major, _ = strconv.ParseUint(m[1], 10, 64)
+	v.Minor, _ = strconv.ParseUint(m[2], 10, 64)
+	v.Build, _ = strconv.ParseUint(m[3], 10, 64)
+	return v, nil
+}
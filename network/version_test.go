@@ -0,0 +1,117 @@
+package net
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	cfg "github.com/vecno-io/go-pyteal/config"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+)
+
+// fakeAlgod answers the capability probes of a 3.2.0 node, its release
+// has teal 5 and the consensus accepts up to 4. Dryruns fail unless
+// dryrunOk is set.
+type fakeAlgod struct {
+	dryruns  int32
+	dryrunOk int32
+}
+
+func (f *fakeAlgod) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/versions":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"build":            map[string]interface{}{"major": 3, "minor": 2, "build_number": 0},
+			"genesis_id":       "caps-v1",
+			"genesis_hash_b64": "Y2FwcyB0ZXN0IGdlbmVzaXMgaGFzaCAzMiBieXRlcyE=",
+			"versions":         []string{"v2"},
+		})
+	case "/v2/status":
+		json.NewEncoder(w).Encode(map[string]interface{}{"last-version": "future"})
+	case "/v2/teal/compile":
+		json.NewEncoder(w).Encode(map[string]interface{}{"hash": "H", "result": "AQ=="})
+	case "/v2/teal/dryrun":
+		atomic.AddInt32(&f.dryruns, 1)
+		if atomic.LoadInt32(&f.dryrunOk) == 0 {
+			http.Error(w, `{"message":"dryrun failed"}`, http.StatusInternalServerError)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		req := models.DryrunRequest{}
+		if err := msgpack.Decode(data, &req); nil != err {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		version, _ := binary.Uvarint(req.Txns[0].Lsig.Logic)
+		msg := "PASS"
+		if version > 4 {
+			msg = "REJECT"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"txns": []interface{}{map[string]interface{}{"logic-sig-messages": []string{msg}}},
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestCapabilitiesCache(t *testing.T) {
+	path := setupDataPath(t)
+	bin := "#!/bin/sh\necho 12885032960\necho '3.2.0.stable [rel/stable] (commit #0)'\n"
+	if err := os.WriteFile(filepath.Join(cfg.NodePath(), "algod"), []byte(bin), 0700); nil != err {
+		t.Fatal(err)
+	}
+	algod := &fakeAlgod{}
+	srv := httptest.NewServer(algod)
+	defer srv.Close()
+	node := filepath.Join(path, "primary")
+	for name, value := range map[string]string{
+		"algod.net":   strings.TrimPrefix(srv.URL, "http://"),
+		"algod.token": strings.Repeat("a", 64),
+		"algod.pid":   "1",
+	} {
+		if err := os.WriteFile(filepath.Join(node, name), []byte(value+"\n"), 0644); nil != err {
+			t.Fatal(err)
+		}
+	}
+	expect := func(consensus, max uint64) {
+		t.Helper()
+		caps, err := Capabilities()
+		if err != nil {
+			t.Fatalf("capabilities: %s", err)
+		}
+		if caps.ConsensusTealVersion != consensus || caps.MaxTealVersion != max {
+			t.Fatalf("expected consensus %d max %d, got consensus %d max %d",
+				consensus, max, caps.ConsensusTealVersion, caps.MaxTealVersion,
+			)
+		}
+	}
+
+	// A failed dryrun probe falls back to the release table
+	expect(0, 5)
+	atomic.StoreInt32(&algod.dryrunOk, 1)
+	expect(4, 4)
+
+	// The probed result is cached for the node process
+	atomic.StoreInt32(&algod.dryrunOk, 0)
+	probes := atomic.LoadInt32(&algod.dryruns)
+	expect(4, 4)
+	if n := atomic.LoadInt32(&algod.dryruns); n != probes {
+		t.Fatalf("cached capabilities probed again: %d dryruns", n-probes)
+	}
+
+	// A restarted node is probed again
+	if err := os.WriteFile(filepath.Join(node, "algod.pid"), []byte("2\n"), 0644); nil != err {
+		t.Fatal(err)
+	}
+	expect(0, 5)
+}
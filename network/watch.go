@@ -0,0 +1,139 @@
+package net
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// WatchFilter selects the transactions of interest, zero values match all.
+// Address matches the sender or any receiver of a transaction.
+type WatchFilter struct {
+	AppId      uint64
+	AssetId    uint64
+	Address    string
+	Type       types.TxType
+	NotePrefix []byte
+}
+
+// WatchOptions sets the round to start from, zero starts with the next
+// round. Use the round of the last handled event plus one to resume.
+type WatchOptions struct {
+	FromRound uint64
+	Filter    WatchFilter
+}
+
+// WatchEvent holds a matching transaction with its apply data.
+type WatchEvent struct {
+	Round uint64
+	TxId  string
+	Txn   types.SignedTxnWithAD
+}
+
+// Watch follows new rounds and sends matching transactions on the event
+// channel. Both channels are closed when ctx is done or on the first error.
+func Watch(ctx context.Context, opts WatchOptions) (<-chan WatchEvent, <-chan error) {
+	events := make(chan WatchEvent)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(events)
+		defer close(errs)
+
+		cln, err := MakeClient()
+		if err != nil {
+			errs <- fmt.Errorf("watch: make client: %s", err)
+			return
+		}
+		if err := watch(ctx, cln, opts, events); nil != err && nil == ctx.Err() {
+			errs <- err
+		}
+	}()
+
+	return events, errs
+}
+
+func watch(ctx context.Context, cln *algod.Client, opts WatchOptions, events chan<- WatchEvent) error {
+	round := opts.FromRound
+	if round == 0 {
+		status, err := cln.Status().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("watch: client status: %s", err)
+		}
+		round = status.LastRound + 1
+	}
+
+	for {
+		// Wait until the block for `round` is available, the node
+		// returns after about a minute without it on a stalled network
+		status, err := cln.StatusAfterBlock(round - 1).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("watch: wait for round %d: %s", round, err)
+		}
+		if status.LastRound < round {
+			continue
+		}
+		block, err := cln.Block(round).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("watch: get block %d: %s", round, err)
+		}
+
+		for _, stxn := range block.Payset {
+			txn := stxn.SignedTxnWithAD
+			// Blocks strip the genesis fields, needed for the id
+			if stxn.HasGenesisID {
+				txn.Txn.GenesisID = block.GenesisID
+			}
+			if stxn.HasGenesisHash {
+				txn.Txn.GenesisHash = block.GenesisHash
+			}
+			if !opts.Filter.Match(txn) {
+				continue
+			}
+			select {
+			case events <- WatchEvent{
+				Round: round,
+				TxId:  crypto.TransactionIDString(txn.Txn),
+				Txn:   txn,
+			}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		round += 1
+	}
+}
+
+// Match reports whether the transaction passes all set filters.
+func (f WatchFilter) Match(stxn types.SignedTxnWithAD) bool {
+	txn := stxn.Txn
+	if len(f.Type) > 0 && txn.Type != f.Type {
+		return false
+	}
+	if len(f.NotePrefix) > 0 && !bytes.HasPrefix(txn.Note, f.NotePrefix) {
+		return false
+	}
+	if f.AppId > 0 && f.AppId != uint64(txn.ApplicationID) && f.AppId != stxn.ApplicationID {
+		return false
+	}
+	if f.AssetId > 0 {
+		switch f.AssetId {
+		case uint64(txn.XferAsset), uint64(txn.ConfigAsset), uint64(txn.FreezeAsset), stxn.ConfigAsset:
+		default:
+			return false
+		}
+	}
+	if len(f.Address) > 0 {
+		switch f.Address {
+		case txn.Sender.String(), txn.Receiver.String(), txn.CloseRemainderTo.String(),
+			txn.AssetReceiver.String(), txn.AssetCloseTo.String():
+		default:
+			return false
+		}
+	}
+	return true
+}